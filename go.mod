@@ -5,6 +5,7 @@ go 1.22
 require (
 	github.com/go-netty/go-netty v1.6.7
 	github.com/go-netty/go-netty-transport v1.7.13
+	golang.org/x/term v0.27.0
 )
 
 require (
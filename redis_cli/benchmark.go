@@ -0,0 +1,256 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redis"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// benchmarkTests are the commands a benchmark mix is made of, like the
+// redis-benchmark tests. key is random within the keyspace, data is the
+// payload.
+var benchmarkTests = map[string]func(key, data string) []interface{}{
+	"ping":  func(key, data string) []interface{} { return []interface{}{"PING"} },
+	"set":   func(key, data string) []interface{} { return []interface{}{"SET", "key:" + key, data} },
+	"get":   func(key, data string) []interface{} { return []interface{}{"GET", "key:" + key} },
+	"incr":  func(key, data string) []interface{} { return []interface{}{"INCR", "counter:" + key} },
+	"lpush": func(key, data string) []interface{} { return []interface{}{"LPUSH", "mylist", data} },
+	"rpush": func(key, data string) []interface{} { return []interface{}{"RPUSH", "mylist", data} },
+	"lpop":  func(key, data string) []interface{} { return []interface{}{"LPOP", "mylist"} },
+	"rpop":  func(key, data string) []interface{} { return []interface{}{"RPOP", "mylist"} },
+	"sadd":  func(key, data string) []interface{} { return []interface{}{"SADD", "myset", "element:" + key} },
+	"spop":  func(key, data string) []interface{} { return []interface{}{"SPOP", "myset"} },
+	"hset":  func(key, data string) []interface{} { return []interface{}{"HSET", "myhash", "element:" + key, data} },
+	"zadd":  func(key, data string) []interface{} { return []interface{}{"ZADD", "myzset", 0, "element:" + key} },
+}
+
+// benchmarkMix picks the command of every request by weight.
+type benchmarkMix struct {
+	names   []string
+	weights []int
+	total   int
+}
+
+// parseMix parses a comma separated list of tests with optional weights,
+// such as "get:9,set:1".
+func parseMix(spec string) (*benchmarkMix, error) {
+	mix := &benchmarkMix{}
+	for _, item := range strings.Split(spec, ",") {
+		name, weight := strings.ToLower(strings.TrimSpace(item)), 1
+		if i := strings.IndexByte(name, ':'); i >= 0 {
+			n, err := strconv.Atoi(name[i+1:])
+			if nil != err || n <= 0 {
+				return nil, fmt.Errorf("invalid weight in %q", item)
+			}
+			name, weight = name[:i], n
+		}
+		if _, ok := benchmarkTests[name]; !ok {
+			return nil, fmt.Errorf("unknown test %q", name)
+		}
+		mix.names = append(mix.names, name)
+		mix.weights = append(mix.weights, weight)
+		mix.total += weight
+	}
+	return mix, nil
+}
+
+func (m *benchmarkMix) pick(rng *rand.Rand) string {
+	n := rng.Intn(m.total)
+	for i, weight := range m.weights {
+		if n < weight {
+			return m.names[i]
+		}
+		n -= weight
+	}
+	return m.names[len(m.names)-1]
+}
+
+// benchmarkResult is what one client measured.
+type benchmarkResult struct {
+	latency map[string]*latencyStats
+	errors  int
+	err     error
+}
+
+// runBenchmark implements the benchmark subcommand: it opens one channel
+// per client, runs the command mix with pipelining and reports throughput
+// and latency percentiles per command.
+func runBenchmark(options *cliOptions, args []string) int {
+
+	flags := flag.NewFlagSet("benchmark", flag.ExitOnError)
+	clients := flags.Int("c", 50, "number of parallel connections")
+	requests := flags.Int("n", 100000, "total number of requests")
+	pipeline := flags.Int("P", 1, "pipeline `depth`, requests in flight per connection")
+	size := flags.Int("d", 3, "data size of SET, LPUSH and HSET values in bytes")
+	keyspace := flags.Int("r", 0, "use random keys out of `keyspace` keys instead of a single one")
+	tests := flags.String("t", "ping,set,get", "comma separated `tests` with optional weights, such as get:9,set:1")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [OPTIONS] benchmark [BENCHMARK OPTIONS]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	mix, err := parseMix(*tests)
+	if nil != err {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *clients < 1 || *pipeline < 1 {
+		fmt.Fprintln(os.Stderr, "-c and -P must be at least 1")
+		return 1
+	}
+
+	// connect every client before the clock starts.
+	conns := make([]commandClient, 0, *clients)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for len(conns) < *clients {
+		conn, err := options.dial()
+		if nil != err {
+			fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+			return 1
+		}
+		conns = append(conns, conn)
+	}
+
+	var issued int64
+	var wg sync.WaitGroup
+	data := strings.Repeat("x", *size)
+	results := make([]*benchmarkResult, len(conns))
+
+	start := time.Now()
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn commandClient) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(i)))
+			results[i] = runBenchmarkClient(conn, rng, mix, &issued, *requests, *pipeline, *keyspace, data)
+		}(i, conn)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	latency, total, errors := map[string]*latencyStats{}, &latencyStats{}, 0
+	for _, result := range results {
+		if nil != result.err {
+			fmt.Fprintln(os.Stderr, "I/O error:", result.err)
+			return 1
+		}
+		for name, stats := range result.latency {
+			if nil == latency[name] {
+				latency[name] = &latencyStats{}
+			}
+			latency[name].merge(stats)
+			total.merge(stats)
+		}
+		errors += result.errors
+	}
+
+	fmt.Println("====== benchmark ======")
+	fmt.Printf("  %d requests completed in %.2f seconds\n", total.count(), elapsed.Seconds())
+	fmt.Printf("  %d parallel clients, pipeline %d, %d bytes payload\n\n", len(conns), *pipeline, *size)
+
+	names := make([]string, 0, len(latency))
+	for name := range latency {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	row := func(name string, stats *latencyStats) {
+		fmt.Printf("  %-8s %9d %12.2f %9s %9s %9s %9s\n", name, stats.count(),
+			float64(stats.count())/elapsed.Seconds(), ms(stats.percentile(50)),
+			ms(stats.percentile(99)), ms(stats.percentile(99.9)), ms(stats.max))
+	}
+
+	fmt.Printf("  %-8s %9s %12s %9s %9s %9s %9s\n", "command", "requests", "requests/s", "p50 ms", "p99 ms", "p99.9 ms", "max ms")
+	for _, name := range names {
+		row(strings.ToUpper(name), latency[name])
+	}
+	row("total", total)
+
+	if errors > 0 {
+		fmt.Printf("\n  %d error replies\n", errors)
+	}
+	return 0
+}
+
+// runBenchmarkClient sends batches of pipeline requests on conn until
+// requests have been issued by all clients together, each latency is
+// measured from the start of its batch.
+func runBenchmarkClient(conn commandClient, rng *rand.Rand, mix *benchmarkMix, issued *int64,
+	requests, pipeline, keyspace int, data string) *benchmarkResult {
+
+	result := &benchmarkResult{latency: map[string]*latencyStats{}}
+	names := make([]string, 0, pipeline)
+	futures := make([]*redis.Future, 0, pipeline)
+
+	for {
+		batch := pipeline
+		if over := int(atomic.AddInt64(issued, int64(pipeline))) - requests; over > 0 {
+			batch -= over
+		}
+		if batch <= 0 {
+			return result
+		}
+
+		names, futures = names[:0], futures[:0]
+		start := time.Now()
+		for i := 0; i < batch; i++ {
+			key := "000000000000"
+			if keyspace > 0 {
+				key = fmt.Sprintf("%012d", rng.Intn(keyspace))
+			}
+			name := mix.pick(rng)
+			names = append(names, name)
+			futures = append(futures, conn.Send(benchmarkTests[name](key, data)...))
+		}
+
+		for i, future := range futures {
+			resp, err := future.Wait()
+			if nil != err {
+				result.err = err
+				return result
+			}
+
+			stats := result.latency[names[i]]
+			if nil == stats {
+				stats = &latencyStats{}
+				result.latency[names[i]] = stats
+			}
+			stats.add(time.Since(start))
+
+			if resp.Kind == redisgo.ErrorKind || resp.Kind == redisgo.BlobErrorKind {
+				result.errors++
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseMix(t *testing.T) {
+	tests := []struct {
+		spec    string
+		names   string
+		weights []int
+		wantErr bool
+	}{
+		{"get", "get", []int{1}, false},
+		{" GET:9 , set ", "get,set", []int{9, 1}, false},
+		{"ping,incr:3,zadd:2", "ping,incr,zadd", []int{1, 3, 2}, false},
+		{"", "", nil, true},
+		{"get,", "", nil, true},
+		{"foo", "", nil, true},
+		{"get:", "", nil, true},
+		{"get:0", "", nil, true},
+		{"get:-1", "", nil, true},
+		{"get:x", "", nil, true},
+		{"get:1:2", "", nil, true},
+	}
+	for _, tt := range tests {
+		mix, err := parseMix(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMix(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		total := 0
+		for i, weight := range tt.weights {
+			total += weight
+			if mix.weights[i] != weight {
+				t.Errorf("parseMix(%q) weights = %v, want %v", tt.spec, mix.weights, tt.weights)
+				break
+			}
+		}
+		if strings.Join(mix.names, ",") != tt.names || mix.total != total {
+			t.Errorf("parseMix(%q) = %v, total %d, want %s, total %d", tt.spec, mix.names, mix.total, tt.names, total)
+		}
+	}
+}
+
+func TestBenchmarkMix_Pick(t *testing.T) {
+	mix, err := parseMix("get:3,set:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 4000; i++ {
+		counts[mix.pick(rng)]++
+	}
+	if counts["get"] < 2700 || counts["get"] > 3300 || counts["get"]+counts["set"] != 4000 {
+		t.Errorf("pick() counts = %v, want about 3000 get and 1000 set", counts)
+	}
+}
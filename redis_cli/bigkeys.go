@@ -0,0 +1,237 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redis"
+)
+
+// keyTypes are the types --bigkeys sizes, with the command that does it
+// and the unit of the size.
+var keyTypes = []struct {
+	name    string
+	command string
+	unit    string
+}{
+	{"string", "STRLEN", "bytes"},
+	{"list", "LLEN", "items"},
+	{"set", "SCARD", "members"},
+	{"hash", "HLEN", "fields"},
+	{"zset", "ZCARD", "members"},
+	{"stream", "XLEN", "entries"},
+}
+
+// keyTypeStats sums up the keys of one type.
+type keyTypeStats struct {
+	keys    int64
+	total   int64
+	biggest string
+	size    int64
+}
+
+// scanOptions converts --pattern and --count.
+func (o *cliOptions) scanOptions() []redis.ScanOption {
+	var options []redis.ScanOption
+	if "" != o.pattern {
+		options = append(options, redis.WithMatch(o.pattern))
+	}
+	if o.count > 0 {
+		options = append(options, redis.WithCount(o.count))
+	}
+	return options
+}
+
+// runScan prints every key matching --pattern, one per line.
+func runScan(options *cliOptions) int {
+
+	client, err := options.dial()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+		return 1
+	}
+	defer client.Close()
+
+	it := redis.NewScanIterator(client, "SCAN", "", options.scanOptions()...)
+	for it.Next(context.Background()) {
+		fmt.Println(it.Val())
+	}
+	if nil != it.Err() {
+		fmt.Fprintln(os.Stderr, "SCAN failed:", it.Err())
+		return 1
+	}
+	return 0
+}
+
+// runBigKeys walks the keyspace and reports the biggest key of each type,
+// by number of elements or, for --memkeys, by MEMORY USAGE. Every batch of
+// keys is sized with pipelined TYPE and size commands.
+func runBigKeys(options *cliOptions, memory bool) int {
+
+	client, err := options.dial()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	var total int64
+	if resp, err := client.Do(ctx, "DBSIZE"); nil == err {
+		total, _ = strconv.ParseInt(resp.Data, 10, 64)
+	}
+
+	what := "biggest keys"
+	if memory {
+		what = "keys consuming the most memory"
+	}
+	fmt.Printf("\n# Scanning the entire keyspace to find %s\n# as well as average sizes per key type.\n\n", what)
+
+	stats := map[string]*keyTypeStats{}
+	var sampled, keyLength int64
+
+	// size a batch of keys, returns false once a command failed.
+	measure := func(keys []string) bool {
+		types := make([]*redis.Future, len(keys))
+		for i, key := range keys {
+			types[i] = client.Send("TYPE", key)
+		}
+
+		names := make([]string, len(keys))
+		sizes := make([]*redis.Future, len(keys))
+		for i, future := range types {
+			resp, err := future.Wait()
+			if err = errorReply(resp, err); nil != err {
+				fmt.Fprintln(os.Stderr, "TYPE failed:", err)
+				return false
+			}
+			names[i] = resp.Data
+
+			switch {
+			case memory:
+				sizes[i] = client.Send("MEMORY", "USAGE", keys[i])
+			default:
+				for _, keyType := range keyTypes {
+					if keyType.name == names[i] {
+						sizes[i] = client.Send(keyType.command, keys[i])
+					}
+				}
+			}
+		}
+
+		for i, future := range sizes {
+			if nil == future {
+				// a module type, or the key is gone.
+				continue
+			}
+			resp, err := future.Wait()
+			if err = errorReply(resp, err); nil != err {
+				fmt.Fprintf(os.Stderr, "size of %s failed: %v\n", quote(keys[i]), err)
+				return false
+			}
+			size, _ := strconv.ParseInt(resp.Data, 10, 64)
+
+			sampled++
+			keyLength += int64(len(keys[i]))
+
+			s := stats[names[i]]
+			if nil == s {
+				s = &keyTypeStats{}
+				stats[names[i]] = s
+			}
+			s.keys++
+			s.total += size
+			if size > s.size || "" == s.biggest {
+				s.biggest, s.size = keys[i], size
+				var progress float64
+				if total > 0 {
+					progress = 100 * float64(sampled) / float64(total)
+				}
+				fmt.Printf("[%05.2f%%] Biggest %-6s found so far %s with %d %s\n",
+					progress, names[i], quote(keys[i]), size, sizeUnit(names[i], memory))
+			}
+		}
+		return true
+	}
+
+	it := redis.NewScanIterator(client, "SCAN", "", options.scanOptions()...)
+	batch := make([]string, 0, 100)
+	for it.Next(ctx) {
+		if batch = append(batch, it.Val()); len(batch) == cap(batch) {
+			if !measure(batch) {
+				return 1
+			}
+			batch = batch[:0]
+		}
+	}
+	if nil != it.Err() {
+		fmt.Fprintln(os.Stderr, "SCAN failed:", it.Err())
+		return 1
+	}
+	if !measure(batch) {
+		return 1
+	}
+
+	fmt.Printf("\n-------- summary -------\n\n")
+	fmt.Printf("Sampled %d keys in the keyspace!\n", sampled)
+	var avgLength float64
+	if sampled > 0 {
+		avgLength = float64(keyLength) / float64(sampled)
+	}
+	fmt.Printf("Total key length in bytes is %d (avg len %.2f)\n\n", keyLength, avgLength)
+
+	for _, keyType := range keyTypes {
+		if s := stats[keyType.name]; nil != s {
+			fmt.Printf("Biggest %6s found %s has %d %s\n", keyType.name, quote(s.biggest), s.size, sizeUnit(keyType.name, memory))
+		}
+	}
+	fmt.Println()
+
+	for _, keyType := range keyTypes {
+		s := stats[keyType.name]
+		if nil == s {
+			s = &keyTypeStats{}
+		}
+		var share, avg float64
+		if sampled > 0 {
+			share = 100 * float64(s.keys) / float64(sampled)
+		}
+		if s.keys > 0 {
+			avg = float64(s.total) / float64(s.keys)
+		}
+		fmt.Printf("%d %ss with %d %s (%05.2f%% of keys, avg size %.2f)\n",
+			s.keys, keyType.name, s.total, sizeUnit(keyType.name, memory), share, avg)
+	}
+	return 0
+}
+
+// sizeUnit names what the size of a key of keyType counts.
+func sizeUnit(keyType string, memory bool) string {
+	if memory {
+		return "bytes"
+	}
+	for _, t := range keyTypes {
+		if t.name == keyType {
+			return t.unit
+		}
+	}
+	return "elements"
+}
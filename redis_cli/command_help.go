@@ -0,0 +1,456 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// commandHelp documents a command for help, completion and hints.
+type commandHelp struct {
+	name    string // upper case, container commands include the subcommand
+	params  string
+	summary string
+	since   string
+	group   string
+}
+
+// commandTable follows the layout of the redis-cli help table.
+var commandTable = []commandHelp{
+	// generic
+	{"COPY", "source destination [DB destination-db] [REPLACE]", "Copies the value of a key to a new key", "6.2.0", "generic"},
+	{"DEL", "key [key ...]", "Deletes one or more keys", "1.0.0", "generic"},
+	{"DUMP", "key", "Returns a serialized representation of the value stored at a key", "2.6.0", "generic"},
+	{"EXISTS", "key [key ...]", "Determines whether one or more keys exist", "1.0.0", "generic"},
+	{"EXPIRE", "key seconds [NX|XX|GT|LT]", "Sets the expiration time of a key in seconds", "1.0.0", "generic"},
+	{"EXPIREAT", "key unix-time-seconds [NX|XX|GT|LT]", "Sets the expiration time of a key to a Unix timestamp", "1.2.0", "generic"},
+	{"EXPIRETIME", "key", "Returns the expiration time of a key as a Unix timestamp", "7.0.0", "generic"},
+	{"KEYS", "pattern", "Returns all key names that match a pattern", "1.0.0", "generic"},
+	{"MOVE", "key db", "Moves a key to another database", "1.0.0", "generic"},
+	{"OBJECT ENCODING", "key", "Returns the internal encoding of a Redis object", "2.2.3", "generic"},
+	{"OBJECT FREQ", "key", "Returns the logarithmic access frequency counter of a Redis object", "4.0.0", "generic"},
+	{"OBJECT IDLETIME", "key", "Returns the time since the last access to a Redis object", "2.2.3", "generic"},
+	{"PERSIST", "key", "Removes the expiration time of a key", "2.2.0", "generic"},
+	{"PEXPIRE", "key milliseconds [NX|XX|GT|LT]", "Sets the expiration time of a key in milliseconds", "2.6.0", "generic"},
+	{"PTTL", "key", "Returns the expiration time in milliseconds of a key", "2.6.0", "generic"},
+	{"RANDOMKEY", "", "Returns a random key name from the database", "1.0.0", "generic"},
+	{"RENAME", "key newkey", "Renames a key and overwrites the destination", "1.0.0", "generic"},
+	{"RENAMENX", "key newkey", "Renames a key only when the target key name doesn't exist", "1.0.0", "generic"},
+	{"RESTORE", "key ttl serialized-value [REPLACE] [ABSTTL] [IDLETIME seconds] [FREQ frequency]", "Creates a key from the serialized representation of a value", "2.6.0", "generic"},
+	{"SCAN", "cursor [MATCH pattern] [COUNT count] [TYPE type]", "Iterates over the key names in the database", "2.8.0", "generic"},
+	{"SORT", "key [BY pattern] [LIMIT offset count] [GET pattern [GET pattern ...]] [ASC|DESC] [ALPHA] [STORE destination]", "Sorts the elements in a list, a set, or a sorted set, optionally storing the result", "1.0.0", "generic"},
+	{"TOUCH", "key [key ...]", "Returns the number of existing keys out of those specified after updating the time they were last accessed", "3.2.1", "generic"},
+	{"TTL", "key", "Returns the expiration time in seconds of a key", "1.0.0", "generic"},
+	{"TYPE", "key", "Determines the type of value stored at a key", "1.0.0", "generic"},
+	{"UNLINK", "key [key ...]", "Asynchronously deletes one or more keys", "4.0.0", "generic"},
+	{"WAIT", "numreplicas timeout", "Blocks until the asynchronous replication of all preceding write commands sent by the connection is completed", "3.0.0", "generic"},
+
+	// string
+	{"APPEND", "key value", "Appends a string to the value of a key. Creates the key if it doesn't exist", "2.0.0", "string"},
+	{"DECR", "key", "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist", "1.0.0", "string"},
+	{"DECRBY", "key decrement", "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist", "1.0.0", "string"},
+	{"GET", "key", "Returns the string value of a key", "1.0.0", "string"},
+	{"GETDEL", "key", "Returns the string value of a key after deleting the key", "6.2.0", "string"},
+	{"GETEX", "key [EX seconds|PX milliseconds|EXAT unix-time-seconds|PXAT unix-time-milliseconds|PERSIST]", "Returns the string value of a key after setting its expiration time", "6.2.0", "string"},
+	{"GETRANGE", "key start end", "Returns a substring of the string stored at a key", "2.4.0", "string"},
+	{"INCR", "key", "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist", "1.0.0", "string"},
+	{"INCRBY", "key increment", "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist", "1.0.0", "string"},
+	{"INCRBYFLOAT", "key increment", "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist", "2.6.0", "string"},
+	{"LCS", "key1 key2 [LEN] [IDX] [MINMATCHLEN min-match-len] [WITHMATCHLEN]", "Finds the longest common substring", "7.0.0", "string"},
+	{"MGET", "key [key ...]", "Atomically returns the string values of one or more keys", "1.0.0", "string"},
+	{"MSET", "key value [key value ...]", "Atomically creates or modifies the string values of one or more keys", "1.0.1", "string"},
+	{"MSETNX", "key value [key value ...]", "Atomically modifies the string values of one or more keys only when all keys don't exist", "1.0.1", "string"},
+	{"SET", "key value [NX|XX] [GET] [EX seconds|PX milliseconds|EXAT unix-time-seconds|PXAT unix-time-milliseconds|KEEPTTL]", "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist", "1.0.0", "string"},
+	{"SETRANGE", "key offset value", "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist", "2.2.0", "string"},
+	{"STRLEN", "key", "Returns the length of a string value", "2.2.0", "string"},
+
+	// list
+	{"BLMOVE", "source destination LEFT|RIGHT LEFT|RIGHT timeout", "Pops an element from a list, pushes it to another list and returns it. Blocks until an element is available otherwise", "6.2.0", "list"},
+	{"BLPOP", "key [key ...] timeout", "Removes and returns the first element in a list. Blocks until an element is available otherwise", "2.0.0", "list"},
+	{"BRPOP", "key [key ...] timeout", "Removes and returns the last element in a list. Blocks until an element is available otherwise", "2.0.0", "list"},
+	{"LINDEX", "key index", "Returns an element from a list by its index", "1.0.0", "list"},
+	{"LINSERT", "key BEFORE|AFTER pivot element", "Inserts an element before or after another element in a list", "2.2.0", "list"},
+	{"LLEN", "key", "Returns the length of a list", "1.0.0", "list"},
+	{"LMOVE", "source destination LEFT|RIGHT LEFT|RIGHT", "Returns an element after popping it from one list and pushing it to another", "6.2.0", "list"},
+	{"LPOP", "key [count]", "Returns the first elements in a list after removing it", "1.0.0", "list"},
+	{"LPOS", "key element [RANK rank] [COUNT num-matches] [MAXLEN len]", "Returns the index of matching elements in a list", "6.0.6", "list"},
+	{"LPUSH", "key element [element ...]", "Prepends one or more elements to a list. Creates the key if it doesn't exist", "1.0.0", "list"},
+	{"LPUSHX", "key element [element ...]", "Prepends one or more elements to a list only when the list exists", "2.2.0", "list"},
+	{"LRANGE", "key start stop", "Returns a range of elements from a list", "1.0.0", "list"},
+	{"LREM", "key count element", "Removes elements from a list", "1.0.0", "list"},
+	{"LSET", "key index element", "Sets the value of an element in a list by its index", "1.0.0", "list"},
+	{"LTRIM", "key start stop", "Removes elements from both ends a list", "1.0.0", "list"},
+	{"RPOP", "key [count]", "Returns and removes the last elements of a list", "1.0.0", "list"},
+	{"RPUSH", "key element [element ...]", "Appends one or more elements to a list. Creates the key if it doesn't exist", "1.0.0", "list"},
+	{"RPUSHX", "key element [element ...]", "Appends an element to a list only when the list exists", "2.2.0", "list"},
+
+	// set
+	{"SADD", "key member [member ...]", "Adds one or more members to a set. Creates the key if it doesn't exist", "1.0.0", "set"},
+	{"SCARD", "key", "Returns the number of members in a set", "1.0.0", "set"},
+	{"SDIFF", "key [key ...]", "Returns the difference of multiple sets", "1.0.0", "set"},
+	{"SDIFFSTORE", "destination key [key ...]", "Stores the difference of multiple sets in a key", "1.0.0", "set"},
+	{"SINTER", "key [key ...]", "Returns the intersect of multiple sets", "1.0.0", "set"},
+	{"SINTERCARD", "numkeys key [key ...] [LIMIT limit]", "Returns the number of members of the intersect of multiple sets", "7.0.0", "set"},
+	{"SINTERSTORE", "destination key [key ...]", "Stores the intersect of multiple sets in a key", "1.0.0", "set"},
+	{"SISMEMBER", "key member", "Determines whether a member belongs to a set", "1.0.0", "set"},
+	{"SMEMBERS", "key", "Returns all members of a set", "1.0.0", "set"},
+	{"SMISMEMBER", "key member [member ...]", "Determines whether multiple members belong to a set", "6.2.0", "set"},
+	{"SMOVE", "source destination member", "Moves a member from one set to another", "1.0.0", "set"},
+	{"SPOP", "key [count]", "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped", "1.0.0", "set"},
+	{"SRANDMEMBER", "key [count]", "Get one or multiple random members from a set", "1.0.0", "set"},
+	{"SREM", "key member [member ...]", "Removes one or more members from a set. Deletes the set if the last member was removed", "1.0.0", "set"},
+	{"SSCAN", "key cursor [MATCH pattern] [COUNT count]", "Iterates over members of a set", "2.8.0", "set"},
+	{"SUNION", "key [key ...]", "Returns the union of multiple sets", "1.0.0", "set"},
+	{"SUNIONSTORE", "destination key [key ...]", "Stores the union of multiple sets in a key", "1.0.0", "set"},
+
+	// sorted-set
+	{"BZPOPMAX", "key [key ...] timeout", "Removes and returns the member with the highest score from one or more sorted sets. Blocks until a member available otherwise", "5.0.0", "sorted-set"},
+	{"BZPOPMIN", "key [key ...] timeout", "Removes and returns the member with the lowest score from one or more sorted sets. Blocks until a member is available otherwise", "5.0.0", "sorted-set"},
+	{"ZADD", "key [NX|XX] [GT|LT] [CH] [INCR] score member [score member ...]", "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist", "1.2.0", "sorted-set"},
+	{"ZCARD", "key", "Returns the number of members in a sorted set", "1.2.0", "sorted-set"},
+	{"ZCOUNT", "key min max", "Returns the count of members in a sorted set that have scores within a range", "2.0.0", "sorted-set"},
+	{"ZINCRBY", "key increment member", "Increments the score of a member in a sorted set", "1.2.0", "sorted-set"},
+	{"ZMSCORE", "key member [member ...]", "Returns the score of one or more members in a sorted set", "6.2.0", "sorted-set"},
+	{"ZPOPMAX", "key [count]", "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped", "5.0.0", "sorted-set"},
+	{"ZPOPMIN", "key [count]", "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped", "5.0.0", "sorted-set"},
+	{"ZRANDMEMBER", "key [count [WITHSCORES]]", "Returns one or more random members from a sorted set", "6.2.0", "sorted-set"},
+	{"ZRANGE", "key start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count] [WITHSCORES]", "Returns members in a sorted set within a range of indexes", "1.2.0", "sorted-set"},
+	{"ZRANGEBYSCORE", "key min max [WITHSCORES] [LIMIT offset count]", "Returns members in a sorted set within a range of scores", "1.0.5", "sorted-set"},
+	{"ZRANK", "key member [WITHSCORE]", "Returns the index of a member in a sorted set ordered by ascending scores", "2.0.0", "sorted-set"},
+	{"ZREM", "key member [member ...]", "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed", "1.2.0", "sorted-set"},
+	{"ZREMRANGEBYRANK", "key start stop", "Removes members in a sorted set within a range of indexes. Deletes the sorted set if all members were removed", "2.0.0", "sorted-set"},
+	{"ZREMRANGEBYSCORE", "key min max", "Removes members in a sorted set within a range of scores. Deletes the sorted set if all members were removed", "1.2.0", "sorted-set"},
+	{"ZREVRANGE", "key start stop [WITHSCORES]", "Returns members in a sorted set within a range of indexes in reverse order", "1.2.0", "sorted-set"},
+	{"ZREVRANK", "key member [WITHSCORE]", "Returns the index of a member in a sorted set ordered by descending scores", "2.0.0", "sorted-set"},
+	{"ZSCAN", "key cursor [MATCH pattern] [COUNT count]", "Iterates over members and scores of a sorted set", "2.8.0", "sorted-set"},
+	{"ZSCORE", "key member", "Returns the score of a member in a sorted set", "1.2.0", "sorted-set"},
+	{"ZUNIONSTORE", "destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX]", "Stores the union of multiple sorted sets in a key", "2.0.0", "sorted-set"},
+
+	// hash
+	{"HDEL", "key field [field ...]", "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain", "2.0.0", "hash"},
+	{"HEXISTS", "key field", "Determines whether a field exists in a hash", "2.0.0", "hash"},
+	{"HGET", "key field", "Returns the value of a field in a hash", "2.0.0", "hash"},
+	{"HGETALL", "key", "Returns all fields and values in a hash", "2.0.0", "hash"},
+	{"HINCRBY", "key field increment", "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist", "2.0.0", "hash"},
+	{"HINCRBYFLOAT", "key field increment", "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist", "2.6.0", "hash"},
+	{"HKEYS", "key", "Returns all fields in a hash", "2.0.0", "hash"},
+	{"HLEN", "key", "Returns the number of fields in a hash", "2.0.0", "hash"},
+	{"HMGET", "key field [field ...]", "Returns the values of all fields in a hash", "2.0.0", "hash"},
+	{"HRANDFIELD", "key [count [WITHVALUES]]", "Returns one or more random fields from a hash", "6.2.0", "hash"},
+	{"HSCAN", "key cursor [MATCH pattern] [COUNT count] [NOVALUES]", "Iterates over fields and values of a hash", "2.8.0", "hash"},
+	{"HSET", "key field value [field value ...]", "Creates or modifies the value of a field in a hash", "2.0.0", "hash"},
+	{"HSETNX", "key field value", "Sets the value of a field in a hash only when the field doesn't exist", "2.0.0", "hash"},
+	{"HSTRLEN", "key field", "Returns the length of the value of a field", "3.2.0", "hash"},
+	{"HVALS", "key", "Returns all values in a hash", "2.0.0", "hash"},
+
+	// pubsub
+	{"PSUBSCRIBE", "pattern [pattern ...]", "Listens for messages published to channels that match one or more patterns", "2.0.0", "pubsub"},
+	{"PUBLISH", "channel message", "Posts a message to a channel", "2.0.0", "pubsub"},
+	{"PUBSUB CHANNELS", "[pattern]", "Returns the active channels", "2.8.0", "pubsub"},
+	{"PUBSUB NUMSUB", "[channel [channel ...]]", "Returns a count of subscribers to channels", "2.8.0", "pubsub"},
+	{"PUNSUBSCRIBE", "[pattern [pattern ...]]", "Stops listening to messages published to channels that match one or more patterns", "2.0.0", "pubsub"},
+	{"SUBSCRIBE", "channel [channel ...]", "Listens for messages published to channels", "2.0.0", "pubsub"},
+	{"UNSUBSCRIBE", "[channel [channel ...]]", "Stops listening to messages posted to channels", "2.0.0", "pubsub"},
+
+	// transactions
+	{"DISCARD", "", "Discards a transaction", "2.0.0", "transactions"},
+	{"EXEC", "", "Executes all commands in a transaction", "1.2.0", "transactions"},
+	{"MULTI", "", "Starts a transaction", "1.2.0", "transactions"},
+	{"UNWATCH", "", "Forgets about watched keys of a transaction", "2.2.0", "transactions"},
+	{"WATCH", "key [key ...]", "Monitors changes to keys to determine the execution of a transaction", "2.2.0", "transactions"},
+
+	// connection
+	{"AUTH", "[username] password", "Authenticates the connection", "1.0.0", "connection"},
+	{"CLIENT GETNAME", "", "Returns the name of the connection", "2.6.9", "connection"},
+	{"CLIENT ID", "", "Returns the unique client ID of the connection", "5.0.0", "connection"},
+	{"CLIENT INFO", "", "Returns information about the connection", "6.2.0", "connection"},
+	{"CLIENT KILL", "ip:port|[ID client-id] [TYPE NORMAL|MASTER|SLAVE|REPLICA|PUBSUB] [USER username] [ADDR ip:port] [LADDR ip:port] [SKIPME YES|NO]", "Terminates open connections", "2.4.0", "connection"},
+	{"CLIENT LIST", "[TYPE NORMAL|MASTER|REPLICA|PUBSUB] [ID client-id [client-id ...]]", "Lists open connections", "2.4.0", "connection"},
+	{"CLIENT SETNAME", "connection-name", "Sets the connection name", "2.6.9", "connection"},
+	{"CLIENT TRACKING", "ON|OFF [REDIRECT client-id] [PREFIX prefix [PREFIX prefix ...]] [BCAST] [OPTIN] [OPTOUT] [NOLOOP]", "Controls server-assisted client-side caching for the connection", "6.0.0", "connection"},
+	{"ECHO", "message", "Returns the given string", "1.0.0", "connection"},
+	{"HELLO", "[protover [AUTH username password] [SETNAME clientname]]", "Handshakes with the Redis server", "6.0.0", "connection"},
+	{"PING", "[message]", "Returns the server's liveliness response", "1.0.0", "connection"},
+	{"QUIT", "", "Closes the connection", "1.0.0", "connection"},
+	{"RESET", "", "Resets the connection", "6.2.0", "connection"},
+	{"SELECT", "index", "Changes the selected database", "1.0.0", "connection"},
+
+	// server
+	{"ACL SETUSER", "username [rule [rule ...]]", "Creates and modifies an ACL user and its rules", "6.0.0", "server"},
+	{"ACL WHOAMI", "", "Returns the authenticated username of the current connection", "6.0.0", "server"},
+	{"BGREWRITEAOF", "", "Asynchronously rewrites the append-only file to disk", "1.0.0", "server"},
+	{"BGSAVE", "[SCHEDULE]", "Asynchronously saves the database(s) to disk", "1.0.0", "server"},
+	{"COMMAND", "", "Returns detailed information about all commands", "2.8.13", "server"},
+	{"COMMAND COUNT", "", "Returns a count of commands", "2.8.13", "server"},
+	{"CONFIG GET", "parameter [parameter ...]", "Returns the effective values of configuration parameters", "2.0.0", "server"},
+	{"CONFIG RESETSTAT", "", "Resets the server's statistics", "2.0.0", "server"},
+	{"CONFIG REWRITE", "", "Persists the effective configuration to file", "2.8.0", "server"},
+	{"CONFIG SET", "parameter value [parameter value ...]", "Sets configuration parameters in-flight", "2.0.0", "server"},
+	{"DBSIZE", "", "Returns the number of keys in the database", "1.0.0", "server"},
+	{"FLUSHALL", "[ASYNC|SYNC]", "Removes all keys from all databases", "1.0.0", "server"},
+	{"FLUSHDB", "[ASYNC|SYNC]", "Remove all keys from the current database", "1.0.0", "server"},
+	{"INFO", "[section [section ...]]", "Returns information and statistics about the server", "1.0.0", "server"},
+	{"LASTSAVE", "", "Returns the Unix timestamp of the last successful save to disk", "1.0.0", "server"},
+	{"MEMORY USAGE", "key [SAMPLES count]", "Estimates the memory usage of a key", "4.0.0", "server"},
+	{"MONITOR", "", "Listens for all requests received by the server in real-time", "1.0.0", "server"},
+	{"REPLICAOF", "host port", "Configures a server as replica of another, or promotes it to a master", "5.0.0", "server"},
+	{"ROLE", "", "Returns the replication role", "2.8.12", "server"},
+	{"SAVE", "", "Synchronously saves the database(s) to disk", "1.0.0", "server"},
+	{"SHUTDOWN", "[NOSAVE|SAVE] [NOW] [FORCE] [ABORT]", "Synchronously saves the database(s) to disk and shuts down the Redis server", "1.0.0", "server"},
+	{"SLOWLOG GET", "[count]", "Returns the slow log's entries", "2.2.12", "server"},
+	{"SWAPDB", "index1 index2", "Swaps two Redis databases", "4.0.0", "server"},
+	{"TIME", "", "Returns the server time", "2.6.0", "server"},
+
+	// scripting
+	{"EVAL", "script numkeys [key [key ...]] [arg [arg ...]]", "Executes a server-side Lua script", "2.6.0", "scripting"},
+	{"EVALSHA", "sha1 numkeys [key [key ...]] [arg [arg ...]]", "Executes a server-side Lua script by SHA1 digest", "2.6.0", "scripting"},
+	{"FCALL", "function numkeys [key [key ...]] [arg [arg ...]]", "Invokes a function", "7.0.0", "scripting"},
+	{"SCRIPT EXISTS", "sha1 [sha1 ...]", "Determines whether server-side Lua scripts exist in the script cache", "2.6.0", "scripting"},
+	{"SCRIPT FLUSH", "[ASYNC|SYNC]", "Removes all server-side Lua scripts from the script cache", "2.6.0", "scripting"},
+	{"SCRIPT LOAD", "script", "Loads a server-side Lua script to the script cache", "2.6.0", "scripting"},
+
+	// hyperloglog
+	{"PFADD", "key [element [element ...]]", "Adds elements to a HyperLogLog key. Creates the key if it doesn't exist", "2.8.9", "hyperloglog"},
+	{"PFCOUNT", "key [key ...]", "Returns the approximated cardinality of the set(s) observed by the HyperLogLog key(s)", "2.8.9", "hyperloglog"},
+	{"PFMERGE", "destkey [sourcekey [sourcekey ...]]", "Merges one or more HyperLogLog values into a single key", "2.8.9", "hyperloglog"},
+
+	// cluster
+	{"ASKING", "", "Signals that a cluster client is following an -ASK redirect", "3.0.0", "cluster"},
+	{"CLUSTER INFO", "", "Returns information about the state of a node", "3.0.0", "cluster"},
+	{"CLUSTER KEYSLOT", "key", "Returns the hash slot for a key", "3.0.0", "cluster"},
+	{"CLUSTER NODES", "", "Returns the cluster configuration for a node", "3.0.0", "cluster"},
+	{"CLUSTER SHARDS", "", "Returns the mapping of cluster slots to shards", "7.0.0", "cluster"},
+	{"CLUSTER SLOTS", "", "Returns the mapping of cluster slots to nodes", "3.0.0", "cluster"},
+	{"READONLY", "", "Enables read-only queries for a connection to a Redis Cluster replica node", "3.0.0", "cluster"},
+	{"READWRITE", "", "Enables read-write queries for a connection to a Reids Cluster replica node", "3.0.0", "cluster"},
+
+	// geo
+	{"GEOADD", "key [NX|XX] [CH] longitude latitude member [longitude latitude member ...]", "Adds one or more members to a geospatial index. The key is created if it doesn't exist", "3.2.0", "geo"},
+	{"GEODIST", "key member1 member2 [M|KM|FT|MI]", "Returns the distance between two members of a geospatial index", "3.2.0", "geo"},
+	{"GEOPOS", "key [member [member ...]]", "Returns the longitude and latitude of members from a geospatial index", "3.2.0", "geo"},
+	{"GEOSEARCH", "key FROMMEMBER member|FROMLONLAT longitude latitude BYRADIUS radius M|KM|FT|MI|BYBOX width height M|KM|FT|MI [ASC|DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]", "Queries a geospatial index for members inside an area of a box or a circle", "6.2.0", "geo"},
+
+	// stream
+	{"XACK", "key group id [id ...]", "Returns the number of messages that were successfully acknowledged by the consumer group member of a stream", "5.0.0", "stream"},
+	{"XADD", "key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]] *|id field value [field value ...]", "Appends a new message to a stream. Creates the key if it doesn't exist", "5.0.0", "stream"},
+	{"XDEL", "key id [id ...]", "Returns the number of messages after removing them from a stream", "5.0.0", "stream"},
+	{"XGROUP CREATE", "key group id|$ [MKSTREAM] [ENTRIESREAD entries-read]", "Creates a consumer group", "5.0.0", "stream"},
+	{"XLEN", "key", "Return the number of messages in a stream", "5.0.0", "stream"},
+	{"XRANGE", "key start end [COUNT count]", "Returns the messages from a stream within a range of IDs", "5.0.0", "stream"},
+	{"XREAD", "[COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]", "Returns messages from multiple streams with IDs greater than the ones requested. Blocks until a message is available otherwise", "5.0.0", "stream"},
+	{"XREADGROUP", "GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]", "Returns new or historical messages from a stream for a consumer in a group. Blocks until a message is available otherwise", "5.0.0", "stream"},
+	{"XTRIM", "key MAXLEN|MINID [=|~] threshold [LIMIT count]", "Deletes messages from the beginning of a stream", "5.0.0", "stream"},
+
+	// bitmap
+	{"BITCOUNT", "key [start end [BYTE|BIT]]", "Counts the number of set bits (population counting) in a string", "2.6.0", "bitmap"},
+	{"BITOP", "AND|OR|XOR|NOT destkey key [key ...]", "Performs bitwise operations on multiple strings, and stores the result", "2.6.0", "bitmap"},
+	{"BITPOS", "key bit [start [end [BYTE|BIT]]]", "Finds the first set (1) or clear (0) bit in a string", "2.8.7", "bitmap"},
+	{"GETBIT", "key offset", "Returns a bit value by offset", "2.2.0", "bitmap"},
+	{"SETBIT", "key offset value", "Sets or clears the bit at offset of the string value. Creates the key if it doesn't exist", "2.2.0", "bitmap"},
+}
+
+// commandGroups lists the groups in table order.
+func commandGroups() []string {
+	var groups []string
+	for i := range commandTable {
+		if 0 == len(groups) || groups[len(groups)-1] != commandTable[i].group {
+			groups = append(groups, commandTable[i].group)
+		}
+	}
+	return groups
+}
+
+// lookupCommand finds the longest entry matching the leading arguments,
+// so "client kill" resolves to CLIENT KILL rather than nothing.
+func lookupCommand(args []string) (*commandHelp, int) {
+	if len(args) > 1 {
+		name := strings.ToUpper(args[0] + " " + args[1])
+		for i := range commandTable {
+			if commandTable[i].name == name {
+				return &commandTable[i], 2
+			}
+		}
+	}
+	if len(args) > 0 {
+		name := strings.ToUpper(args[0])
+		for i := range commandTable {
+			if commandTable[i].name == name {
+				return &commandTable[i], 1
+			}
+		}
+	}
+	return nil, 0
+}
+
+func printCommandHelp(w io.Writer, help *commandHelp) {
+	// colors only make sense on a terminal.
+	if output == outputTTY {
+		fmt.Fprintf(w, "\n  \x1b[1m%s\x1b[0m \x1b[90m%s\x1b[0m\n", help.name, help.params)
+		fmt.Fprintf(w, "  \x1b[33msummary:\x1b[0m %s\n", help.summary)
+		fmt.Fprintf(w, "  \x1b[33msince:\x1b[0m %s\n", help.since)
+		fmt.Fprintf(w, "  \x1b[33mgroup:\x1b[0m %s\n", help.group)
+		return
+	}
+	fmt.Fprintf(w, "\n  %s %s\n", help.name, help.params)
+	fmt.Fprintf(w, "  summary: %s\n", help.summary)
+	fmt.Fprintf(w, "  since: %s\n", help.since)
+	fmt.Fprintf(w, "  group: %s\n", help.group)
+}
+
+// printHelp answers "help", "help <command>" and "help @<group>".
+func printHelp(w io.Writer, args []string) {
+	if 0 == len(args) {
+		fmt.Fprintln(w, "redis_cli, a redis console written by go-netty")
+		fmt.Fprintln(w, "To get help about Redis commands type:")
+		fmt.Fprintln(w, `      "help @<group>" to get a list of commands in <group>`)
+		fmt.Fprintln(w, `      "help <command>" for help on <command>`)
+		fmt.Fprintln(w, `      "help <tab>" to get a list of possible help topics`)
+		fmt.Fprintln(w, `      "exit" to exit`)
+		return
+	}
+
+	if strings.HasPrefix(args[0], "@") {
+		group := strings.ToLower(args[0][1:])
+		found := false
+		for i := range commandTable {
+			if commandTable[i].group == group {
+				printCommandHelp(w, &commandTable[i])
+				found = true
+			}
+		}
+		if !found {
+			fmt.Fprintf(w, "unknown group %q, groups are: @%s\n", args[0][1:], strings.Join(commandGroups(), ", @"))
+		}
+		return
+	}
+
+	if help, _ := lookupCommand(args); nil != help {
+		printCommandHelp(w, help)
+		return
+	}
+	fmt.Fprintf(w, "no help for %q\n", strings.Join(args, " "))
+}
+
+// completeCommand returns the completions of the word being typed at the
+// end of line, the rest of the line is kept as typed.
+func completeCommand(line string) []string {
+	args := strings.Fields(line)
+	if 0 == len(args) || strings.HasSuffix(line, " ") {
+		args = append(args, "")
+	}
+
+	var candidates []string
+	word := args[len(args)-1]
+	lower := word == strings.ToLower(word)
+	head := line[:len(line)-len(word)]
+
+	add := func(candidate string) {
+		if lower {
+			candidate = strings.ToLower(candidate)
+		}
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(word)) {
+			candidates = append(candidates, head+candidate)
+		}
+	}
+
+	switch {
+	case 1 == len(args):
+		// command names, container commands complete their first word only.
+		seen := map[string]bool{"HELP": true}
+		add("HELP")
+		for i := range commandTable {
+			name := strings.Fields(commandTable[i].name)[0]
+			if !seen[name] {
+				seen[name] = true
+				add(name)
+			}
+		}
+	case 2 == len(args) && strings.EqualFold(args[0], "help"):
+		for _, group := range commandGroups() {
+			add("@" + group)
+		}
+		for i := range commandTable {
+			add(commandTable[i].name)
+		}
+	case 2 == len(args):
+		// subcommands of container commands.
+		prefix := strings.ToUpper(args[0]) + " "
+		for i := range commandTable {
+			if strings.HasPrefix(commandTable[i].name, prefix) {
+				add(commandTable[i].name[len(prefix):])
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// commandHint returns the parameters still expected after line, shown
+// greyed out after the cursor.
+func commandHint(line string) string {
+	args, err := redisgo.SplitArgs(line)
+	if nil != err || 0 == len(args) {
+		return ""
+	}
+
+	help, n := lookupCommand(args)
+	if nil == help || "" == help.params {
+		return ""
+	}
+	if !strings.HasSuffix(line, " ") {
+		// hint the whole command once its name is typed.
+		if len(args) == n {
+			return " " + help.params
+		}
+		return ""
+	}
+
+	// drop the mandatory parameters already given.
+	params := splitParams(help.params)
+	for typed := len(args) - n; typed > 0; typed-- {
+		if 0 == len(params) || strings.HasPrefix(params[0], "[") {
+			return ""
+		}
+		params = params[1:]
+	}
+	return strings.Join(params, " ")
+}
+
+// splitParams splits parameters at spaces outside of brackets.
+func splitParams(params string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(params); i++ {
+		switch params[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ' ':
+			if 0 == depth {
+				out = append(out, params[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, params[start:])
+}
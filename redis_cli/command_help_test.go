@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompleteCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"getr", []string{"getrange"}},
+		{"GETR", []string{"GETRANGE"}},
+		{"GeTr", []string{"GETRANGE"}},
+		{"getd", []string{"getdel"}},
+		{"get", []string{"get", "getbit", "getdel", "getex", "getrange"}},
+		{"client getn", []string{"client getname"}},
+		{"CLIENT GETN", []string{"CLIENT GETNAME"}},
+		{"help @str", []string{"help @stream", "help @string"}},
+		{"xyzzy", nil},
+		{"set k ", nil},
+	}
+	for _, tt := range tests {
+		if got := completeCommand(tt.line); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("completeCommand(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCommandHint(t *testing.T) {
+	const setOptions = "[NX|XX] [GET] [EX seconds|PX milliseconds|EXAT unix-time-seconds|PXAT unix-time-milliseconds|KEEPTTL]"
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"set", " key value " + setOptions},
+		{"SET", " key value " + setOptions},
+		{"se", ""},
+		{"set ", "key value " + setOptions},
+		{"set k ", "value " + setOptions},
+		{"set k", ""},
+		{"set k v ", setOptions},
+		{"set k v NX ", ""},
+		{"getrange k ", "start end"},
+		{"zadd k ", "[NX|XX] [GT|LT] [CH] [INCR] score member [score member ...]"},
+		{"client getname", ""},
+		{"foo ", ""},
+		{"set \"k", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := commandHint(tt.line); got != tt.want {
+			t.Errorf("commandHint(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
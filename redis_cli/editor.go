@@ -0,0 +1,473 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// errInterrupted is returned by ReadLine when Ctrl-C is pressed.
+var errInterrupted = errors.New("interrupted")
+
+// maxHistory is the number of lines kept in the history file.
+const maxHistory = 100
+
+// consoleTerm reads console input, output written to it while a line is
+// being edited is printed above the prompt.
+type consoleTerm interface {
+	io.Writer
+	ReadLine(prompt string) (string, error)
+	AddHistory(line string)
+}
+
+// newConsoleTerm returns a line editor for terminals and a plain line
+// reader for pipes and files.
+func newConsoleTerm() consoleTerm {
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		return newLineEditor(historyFile())
+	}
+	return &plainTerm{stdin: bufio.NewScanner(os.Stdin)}
+}
+
+// historyFile follows redis-cli, REDISCLI_HISTFILE overrides the default.
+func historyFile() string {
+	if file, ok := os.LookupEnv("REDISCLI_HISTFILE"); ok {
+		return file
+	}
+	if home, err := os.UserHomeDir(); nil == err {
+		return filepath.Join(home, ".rediscli_history")
+	}
+	return ""
+}
+
+// plainTerm reads lines without editing, as the console did before.
+type plainTerm struct {
+	stdin   *bufio.Scanner
+	mutex   sync.Mutex
+	prompt  string
+	reading bool
+}
+
+func (p *plainTerm) ReadLine(prompt string) (string, error) {
+	p.mutex.Lock()
+	p.prompt, p.reading = prompt, true
+	fmt.Print(prompt)
+	p.mutex.Unlock()
+
+	ok := p.stdin.Scan()
+
+	p.mutex.Lock()
+	p.reading = false
+	p.mutex.Unlock()
+
+	if !ok {
+		if err := p.stdin.Err(); nil != err {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return p.stdin.Text(), nil
+}
+
+func (p *plainTerm) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	n, err := os.Stdout.Write(b)
+	if p.reading {
+		fmt.Print(p.prompt)
+	}
+	return n, err
+}
+
+func (p *plainTerm) AddHistory(line string) {}
+
+// special keys decoded from escape sequences.
+const (
+	keyUp rune = -1 - iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// lineEditor edits a single line in raw mode with emacs style keys,
+// history, tab completion and greyed out hints.
+type lineEditor struct {
+	fd          int
+	in          *bufio.Reader
+	history     []string
+	historyFile string
+	complete    func(line string) []string
+	hint        func(line string) string
+
+	mutex   sync.Mutex
+	reading bool
+	prompt  string
+	line    []rune
+	pos     int
+}
+
+func newLineEditor(historyFile string) *lineEditor {
+	e := &lineEditor{
+		fd:          int(os.Stdin.Fd()),
+		in:          bufio.NewReader(os.Stdin),
+		historyFile: historyFile,
+		complete:    completeCommand,
+		hint:        commandHint,
+	}
+	e.loadHistory()
+	return e
+}
+
+func (e *lineEditor) loadHistory() {
+	if "" == e.historyFile {
+		return
+	}
+	data, err := os.ReadFile(e.historyFile)
+	if nil != err {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if "" != line {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+// AddHistory remembers line and saves the history file, commands that
+// carry passwords are never saved.
+func (e *lineEditor) AddHistory(line string) {
+	if "" == line || sensitive(line) {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+	if "" != e.historyFile {
+		_ = os.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+}
+
+// sensitive reports whether line sends credentials.
+func sensitive(line string) bool {
+	args := strings.Fields(strings.ToLower(line))
+	switch {
+	case 0 == len(args):
+		return false
+	case "auth" == args[0]:
+		return true
+	case "acl" == args[0] && len(args) > 1 && "setuser" == args[1]:
+		return true
+	case "hello" == args[0] || "migrate" == args[0]:
+		for _, arg := range args[1:] {
+			if "auth" == arg || "auth2" == arg {
+				return true
+			}
+		}
+	case "config" == args[0] && len(args) > 2 && "set" == args[1]:
+		return strings.Contains(args[2], "auth") || strings.Contains(args[2], "pass")
+	}
+	return false
+}
+
+// ReadLine reads one line, it returns io.EOF on Ctrl-D at an empty line
+// and errInterrupted on Ctrl-C.
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(e.fd)
+	if nil != err {
+		return "", err
+	}
+	defer term.Restore(e.fd, state)
+
+	e.mutex.Lock()
+	e.reading, e.prompt, e.line, e.pos = true, prompt, nil, 0
+	e.refresh()
+	e.mutex.Unlock()
+
+	// history entry being edited, len(history) is the new line.
+	index, pending := len(e.history), ""
+
+	for {
+		key, err := e.readKey()
+		if nil != err {
+			e.finish("\r\n")
+			return "", err
+		}
+
+		e.mutex.Lock()
+		switch key {
+		case '\r', '\n':
+			line := string(e.line)
+			e.mutex.Unlock()
+			e.finish("\r\n")
+			return line, nil
+		case 3: // Ctrl-C
+			e.mutex.Unlock()
+			e.finish("^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if 0 == len(e.line) {
+				e.mutex.Unlock()
+				e.finish("\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case 127, 8: // Backspace, Ctrl-H
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyDelete:
+			e.deleteAt(e.pos)
+		case 9: // Tab
+			e.completeLine()
+		case 1, keyHome: // Ctrl-A
+			e.pos = 0
+		case 5, keyEnd: // Ctrl-E
+			e.pos = len(e.line)
+		case 2, keyLeft: // Ctrl-B
+			if e.pos > 0 {
+				e.pos--
+			}
+		case 6, keyRight: // Ctrl-F
+			if e.pos < len(e.line) {
+				e.pos++
+			}
+		case 11: // Ctrl-K, delete to the end of line
+			e.line = e.line[:e.pos]
+		case 21: // Ctrl-U, delete the whole line
+			e.line, e.pos = nil, 0
+		case 23: // Ctrl-W, delete the previous word
+			start := e.pos
+			for start > 0 && ' ' == e.line[start-1] {
+				start--
+			}
+			for start > 0 && ' ' != e.line[start-1] {
+				start--
+			}
+			e.line = append(e.line[:start], e.line[e.pos:]...)
+			e.pos = start
+		case 12: // Ctrl-L
+			os.Stdout.WriteString("\x1b[H\x1b[2J")
+		case 16, keyUp: // Ctrl-P
+			if index > 0 {
+				if index == len(e.history) {
+					pending = string(e.line)
+				}
+				index--
+				e.line = []rune(e.history[index])
+				e.pos = len(e.line)
+			}
+		case 14, keyDown: // Ctrl-N
+			if index < len(e.history) {
+				index++
+				if index == len(e.history) {
+					e.line = []rune(pending)
+				} else {
+					e.line = []rune(e.history[index])
+				}
+				e.pos = len(e.line)
+			}
+		default:
+			if key >= ' ' {
+				e.line = append(e.line[:e.pos], append([]rune{key}, e.line[e.pos:]...)...)
+				e.pos++
+			}
+		}
+		e.refresh()
+		e.mutex.Unlock()
+	}
+}
+
+// finish leaves edit mode, the line is redrawn without its hint.
+func (e *lineEditor) finish(end string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.reading = false
+	e.pos = len(e.line)
+	e.refresh()
+	os.Stdout.WriteString(end)
+}
+
+// readKey reads one key press, escape sequences of cursor keys are
+// decoded into special keys.
+func (e *lineEditor) readKey() (rune, error) {
+	key, _, err := e.in.ReadRune()
+	if nil != err || 27 != key {
+		return key, err
+	}
+
+	// ESC [ x, ESC O x, or ESC [ n ~
+	next, _, err := e.in.ReadRune()
+	if nil != err {
+		return 0, err
+	}
+	if '[' != next && 'O' != next {
+		return keyUnknown, nil
+	}
+	code, _, err := e.in.ReadRune()
+	if nil != err {
+		return 0, err
+	}
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	if code < '0' || code > '9' {
+		return keyUnknown, nil
+	}
+	for last := code; '~' != last; {
+		if last, _, err = e.in.ReadRune(); nil != err {
+			return 0, err
+		}
+	}
+	switch code {
+	case '1', '7':
+		return keyHome, nil
+	case '4', '8':
+		return keyEnd, nil
+	case '3':
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
+
+func (e *lineEditor) deleteAt(pos int) {
+	if pos < len(e.line) {
+		e.line = append(e.line[:pos], e.line[pos+1:]...)
+	}
+}
+
+// completeLine completes the line up to the longest common prefix of the
+// candidates, and lists them when there is nothing left to complete.
+func (e *lineEditor) completeLine() {
+	if e.pos != len(e.line) || nil == e.complete {
+		return
+	}
+
+	line := string(e.line)
+	candidates := e.complete(line)
+	switch len(candidates) {
+	case 0:
+		os.Stdout.WriteString("\a")
+		return
+	case 1:
+		e.line = []rune(candidates[0] + " ")
+		e.pos = len(e.line)
+		return
+	}
+
+	common := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(line) {
+		e.line = []rune(common)
+		e.pos = len(e.line)
+		return
+	}
+
+	// list the words being completed.
+	head := strings.LastIndexByte(line, ' ') + 1
+	words := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		words[i] = candidate[head:]
+	}
+	os.Stdout.WriteString("\r\n" + strings.Join(words, "  ") + "\r\n")
+}
+
+// refresh redraws the prompt and line, scrolling horizontally when the
+// line does not fit the terminal.
+func (e *lineEditor) refresh() {
+	cols, _, err := term.GetSize(e.fd)
+	if nil != err || cols <= 0 {
+		cols = 80
+	}
+
+	plen := utf8.RuneCountInString(e.prompt)
+	buf, pos := e.line, e.pos
+	for plen+pos >= cols && pos > 0 {
+		buf, pos = buf[1:], pos-1
+	}
+	if plen+len(buf) > cols {
+		buf = buf[:max(cols-plen, 0)]
+	}
+
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(e.prompt)
+	b.WriteString(string(buf))
+	if e.reading && e.pos == len(e.line) && nil != e.hint {
+		if hint := []rune(e.hint(string(e.line))); len(hint) > 0 {
+			if room := cols - plen - len(buf); len(hint) > room {
+				hint = hint[:max(room, 0)]
+			}
+			b.WriteString("\x1b[90m" + string(hint) + "\x1b[0m")
+		}
+	}
+	b.WriteString("\x1b[0K")
+	fmt.Fprintf(&b, "\r\x1b[%dC", plen+pos)
+	os.Stdout.WriteString(b.String())
+}
+
+// Write prints output above the line being edited and redraws it.
+func (e *lineEditor) Write(p []byte) (int, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.reading {
+		return os.Stdout.Write(p)
+	}
+
+	// the terminal is in raw mode, new lines need a carriage return.
+	text := strings.ReplaceAll(strings.TrimSuffix(string(p), "\n"), "\n", "\r\n")
+	os.Stdout.WriteString("\r\x1b[0K" + text + "\r\n")
+	e.refresh()
+	return len(p), nil
+}
@@ -0,0 +1,299 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redis"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// outputMode selects how replies are printed, like the redis-cli flags.
+type outputMode int
+
+const (
+	outputTTY outputMode = iota
+	outputRaw
+	outputCSV
+	outputJSON
+)
+
+// output is the mode chosen on the command line.
+var output = outputTTY
+
+func printResp(w io.Writer, resp *redisgo.Resp) {
+	io.WriteString(w, formatReply(resp, output))
+}
+
+// errorReply returns the error of a failed request or an error reply.
+func errorReply(resp *redisgo.Resp, err error) error {
+	if nil == err && (resp.Kind == redisgo.ErrorKind || resp.Kind == redisgo.BlobErrorKind) {
+		err = redis.Error(resp.Data)
+	}
+	return err
+}
+
+// formatReply renders resp terminated by a newline.
+func formatReply(resp *redisgo.Resp, mode outputMode) string {
+	switch mode {
+	case outputRaw:
+		return formatRaw(resp) + "\n"
+	case outputCSV:
+		return formatCSV(resp) + "\n"
+	case outputJSON:
+		var b strings.Builder
+		formatJSON(&b, resp)
+		return b.String() + "\n"
+	default:
+		return formatTTY(resp, "")
+	}
+}
+
+// formatTTY renders resp for humans, nested aggregates are indented by
+// prefix on every line but the first.
+func formatTTY(resp *redisgo.Resp, prefix string) string {
+	if resp.Null {
+		return "(nil)\n"
+	}
+
+	switch resp.Kind {
+	case redisgo.ErrorKind, redisgo.BlobErrorKind:
+		return "(error) " + resp.Data + "\n"
+	case redisgo.SimpleKind:
+		return resp.Data + "\n"
+	case redisgo.IntegerKind:
+		return "(integer) " + resp.Data + "\n"
+	case redisgo.DoubleKind:
+		return "(double) " + resp.Data + "\n"
+	case redisgo.BigNumberKind:
+		return "(big number) " + resp.Data + "\n"
+	case redisgo.BooleanKind:
+		if resp.Data == "t" {
+			return "(true)\n"
+		}
+		return "(false)\n"
+	case redisgo.VerbatimKind:
+		return verbatim(resp) + "\n"
+	case redisgo.ArrayKind, redisgo.SetKind, redisgo.PushKind, redisgo.MapKind:
+		return formatAggregate(resp, prefix)
+	default:
+		return quote(resp.Data) + "\n"
+	}
+}
+
+func formatAggregate(resp *redisgo.Resp, prefix string) string {
+
+	n, marker, empty := len(resp.Array), ")", "(empty array)\n"
+	switch resp.Kind {
+	case redisgo.SetKind:
+		marker, empty = "~", "(empty set)\n"
+	case redisgo.PushKind:
+		empty = "(empty push)\n"
+	case redisgo.MapKind:
+		n, marker, empty = len(resp.Map), "#", "(empty hash)\n"
+	}
+	if 0 == n {
+		return empty
+	}
+
+	// elements are aligned after the widest index.
+	width := len(strconv.Itoa(n))
+	indent := prefix + strings.Repeat(" ", width+2)
+
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(prefix)
+		}
+		fmt.Fprintf(&b, "%*d%s ", width, i+1, marker)
+		if resp.Kind == redisgo.MapKind {
+			b.WriteString(strings.TrimSuffix(formatTTY(&resp.Map[i].Key, indent), "\n"))
+			b.WriteString(" => ")
+			b.WriteString(formatTTY(&resp.Map[i].Value, indent))
+		} else {
+			b.WriteString(formatTTY(&resp.Array[i], indent))
+		}
+	}
+	return b.String()
+}
+
+// formatRaw renders values as is, one per line, for scripts.
+func formatRaw(resp *redisgo.Resp) string {
+	if resp.Null {
+		return ""
+	}
+
+	switch resp.Kind {
+	case redisgo.BooleanKind:
+		if resp.Data == "t" {
+			return "(true)"
+		}
+		return "(false)"
+	case redisgo.VerbatimKind:
+		return verbatim(resp)
+	case redisgo.ArrayKind, redisgo.SetKind, redisgo.PushKind:
+		lines := make([]string, len(resp.Array))
+		for i := range resp.Array {
+			lines[i] = formatRaw(&resp.Array[i])
+		}
+		return strings.Join(lines, "\n")
+	case redisgo.MapKind:
+		lines := make([]string, 0, 2*len(resp.Map))
+		for i := range resp.Map {
+			lines = append(lines, formatRaw(&resp.Map[i].Key), formatRaw(&resp.Map[i].Value))
+		}
+		return strings.Join(lines, "\n")
+	default:
+		return resp.Data
+	}
+}
+
+// formatCSV renders values as comma separated fields.
+func formatCSV(resp *redisgo.Resp) string {
+	if resp.Null {
+		return "NULL"
+	}
+
+	switch resp.Kind {
+	case redisgo.ErrorKind, redisgo.BlobErrorKind:
+		return "ERROR," + quote(resp.Data)
+	case redisgo.IntegerKind, redisgo.DoubleKind, redisgo.BigNumberKind:
+		return resp.Data
+	case redisgo.BooleanKind:
+		if resp.Data == "t" {
+			return "true"
+		}
+		return "false"
+	case redisgo.VerbatimKind:
+		return quote(verbatim(resp))
+	case redisgo.ArrayKind, redisgo.SetKind, redisgo.PushKind:
+		fields := make([]string, len(resp.Array))
+		for i := range resp.Array {
+			fields[i] = formatCSV(&resp.Array[i])
+		}
+		return strings.Join(fields, ",")
+	case redisgo.MapKind:
+		fields := make([]string, 0, 2*len(resp.Map))
+		for i := range resp.Map {
+			fields = append(fields, formatCSV(&resp.Map[i].Key), formatCSV(&resp.Map[i].Value))
+		}
+		return strings.Join(fields, ",")
+	default:
+		return quote(resp.Data)
+	}
+}
+
+// formatJSON renders resp as a JSON value, maps keep the order of the reply.
+func formatJSON(b *strings.Builder, resp *redisgo.Resp) {
+	if resp.Null {
+		b.WriteString("null")
+		return
+	}
+
+	switch resp.Kind {
+	case redisgo.ErrorKind, redisgo.BlobErrorKind:
+		b.WriteString(`{"error":`)
+		b.WriteString(jsonString(resp.Data))
+		b.WriteString("}")
+	case redisgo.IntegerKind, redisgo.BigNumberKind:
+		b.WriteString(resp.Data)
+	case redisgo.DoubleKind:
+		// inf and nan have no JSON number.
+		if f, err := strconv.ParseFloat(resp.Data, 64); nil != err || math.IsInf(f, 0) || math.IsNaN(f) {
+			b.WriteString(jsonString(resp.Data))
+		} else {
+			b.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	case redisgo.BooleanKind:
+		b.WriteString(strconv.FormatBool(resp.Data == "t"))
+	case redisgo.VerbatimKind:
+		b.WriteString(jsonString(verbatim(resp)))
+	case redisgo.ArrayKind, redisgo.SetKind, redisgo.PushKind:
+		b.WriteString("[")
+		for i := range resp.Array {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			formatJSON(b, &resp.Array[i])
+		}
+		b.WriteString("]")
+	case redisgo.MapKind:
+		b.WriteString("{")
+		for i := range resp.Map {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(jsonString(formatRaw(&resp.Map[i].Key)))
+			b.WriteString(":")
+			formatJSON(b, &resp.Map[i].Value)
+		}
+		b.WriteString("}")
+	default:
+		b.WriteString(jsonString(resp.Data))
+	}
+}
+
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// verbatim strips the format prefix, such as "txt:", of a verbatim string.
+func verbatim(resp *redisgo.Resp) string {
+	if len(resp.Data) >= 4 && resp.Data[3] == ':' {
+		return resp.Data[4:]
+	}
+	return resp.Data
+}
+
+// quote renders s in double quotes, escaping non printable bytes as
+// redis-cli does.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		default:
+			if c < 0x20 || c > 0x7e {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
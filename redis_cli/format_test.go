@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// decodeResp decodes one reply written in RESP.
+func decodeResp(t *testing.T, s string) *redisgo.Resp {
+	t.Helper()
+	var resp redisgo.Resp
+	if err := redisgo.NewDecoder(strings.NewReader(s), 1024).Decode(&resp); err != nil {
+		t.Fatalf("Decode(%q) error = %v", s, err)
+	}
+	return &resp
+}
+
+func TestFormatReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		mode  outputMode
+		want  string
+	}{
+		{"tty-nil", "$-1\r\n", outputTTY, "(nil)\n"},
+		{"tty-empty-array", "*0\r\n", outputTTY, "(empty array)\n"},
+		{"tty-empty-set", "~0\r\n", outputTTY, "(empty set)\n"},
+		{"tty-integer", ":5\r\n", outputTTY, "(integer) 5\n"},
+		{"tty-error", "-ERR bad\r\n", outputTTY, "(error) ERR bad\n"},
+		{"tty-status", "+OK\r\n", outputTTY, "OK\n"},
+		{"tty-bulk", "$4\r\na\"\n\x01\r\n", outputTTY, `"a\"\n\x01"` + "\n"},
+		{"tty-nested", "*2\r\n$1\r\na\r\n*2\r\n:1\r\n$-1\r\n", outputTTY, "1) \"a\"\n2) 1) (integer) 1\n   2) (nil)\n"},
+		{"tty-wide", "*10\r\n:1\r\n:2\r\n:3\r\n:4\r\n:5\r\n:6\r\n:7\r\n:8\r\n*1\r\n:9\r\n:10\r\n", outputTTY,
+			" 1) (integer) 1\n 2) (integer) 2\n 3) (integer) 3\n 4) (integer) 4\n 5) (integer) 5\n" +
+				" 6) (integer) 6\n 7) (integer) 7\n 8) (integer) 8\n 9) 1) (integer) 9\n10) (integer) 10\n"},
+		{"tty-map", "%2\r\n+a\r\n:1\r\n+b\r\n*1\r\n+c\r\n", outputTTY, "1# a => (integer) 1\n2# b => 1) c\n"},
+		{"tty-boolean", "#t\r\n", outputTTY, "(true)\n"},
+		{"tty-verbatim", "=7\r\ntxt:abc\r\n", outputTTY, "abc\n"},
+		{"raw-bulk", "$3\r\na\nb\r\n", outputRaw, "a\nb\n"},
+		{"raw-array", "*3\r\n$1\r\na\r\n$-1\r\n:2\r\n", outputRaw, "a\n\n2\n"},
+		{"raw-map", "%1\r\n+k\r\n+v\r\n", outputRaw, "k\nv\n"},
+		{"csv-array", "*4\r\n$3\r\na,b\r\n:1\r\n$-1\r\n$2\r\n\"\n\r\n", outputCSV, `"a,b",1,NULL,"\"\n"` + "\n"},
+		{"csv-error", "-ERR bad\r\n", outputCSV, `ERROR,"ERR bad"` + "\n"},
+		{"csv-boolean", "#f\r\n", outputCSV, "false\n"},
+		{"json-array", "*3\r\n$2\r\n\"\n\r\n:1\r\n_\r\n", outputJSON, `["\"\n",1,null]` + "\n"},
+		{"json-map", "%2\r\n+a\r\n,inf\r\n+b\r\n,1.50\r\n", outputJSON, `{"a":"inf","b":1.5}` + "\n"},
+		{"json-error", "-ERR \"x\"\r\n", outputJSON, `{"error":"ERR \"x\""}` + "\n"},
+		{"json-boolean", "#t\r\n", outputJSON, "true\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatReply(decodeResp(t, tt.input), tt.mode); got != tt.want {
+				t.Errorf("formatReply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", `""`},
+		{"plain text", `"plain text"`},
+		{`a\b"c`, `"a\\b\"c"`},
+		{"\r\n\t\a\b", `"\r\n\t\a\b"`},
+		{"\x00\x1f\x7f\xff", `"\x00\x1f\x7f\xff"`},
+	}
+	for _, tt := range tests {
+		if got := quote(tt.input); got != tt.want {
+			t.Errorf("quote(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
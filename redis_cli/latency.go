@@ -0,0 +1,190 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+)
+
+// latencyStats collects request latencies.
+type latencyStats struct {
+	samples  []time.Duration
+	sum      time.Duration
+	min, max time.Duration
+	sorted   bool
+}
+
+func (s *latencyStats) add(d time.Duration) {
+	if 0 == len(s.samples) || d < s.min {
+		s.min = d
+	}
+	if d > s.max {
+		s.max = d
+	}
+	s.samples = append(s.samples, d)
+	s.sum += d
+	s.sorted = false
+}
+
+func (s *latencyStats) merge(other *latencyStats) {
+	for _, d := range other.samples {
+		s.add(d)
+	}
+}
+
+func (s *latencyStats) count() int {
+	return len(s.samples)
+}
+
+func (s *latencyStats) avg() time.Duration {
+	if 0 == len(s.samples) {
+		return 0
+	}
+	return s.sum / time.Duration(len(s.samples))
+}
+
+// percentile returns the latency p percent of the samples do not exceed.
+func (s *latencyStats) percentile(p float64) time.Duration {
+	if 0 == len(s.samples) {
+		return 0
+	}
+	if !s.sorted {
+		sort.Slice(s.samples, func(i, j int) bool { return s.samples[i] < s.samples[j] })
+		s.sorted = true
+	}
+	i := int(float64(len(s.samples))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(s.samples) {
+		i = len(s.samples) - 1
+	}
+	return s.samples[i]
+}
+
+// latencyBuckets are the upper bounds of the histogram rows.
+var latencyBuckets = []time.Duration{
+	100 * time.Microsecond, 200 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond, time.Second,
+}
+
+// printHistogram prints one row per bucket from the fastest to the slowest
+// sample, with the share of samples as a bar.
+func (s *latencyStats) printHistogram(w io.Writer) {
+	if 0 == len(s.samples) {
+		return
+	}
+
+	counts := make([]int, len(latencyBuckets)+1)
+	for _, d := range s.samples {
+		counts[sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })]++
+	}
+
+	first, last := -1, 0
+	for i, count := range counts {
+		if count > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	for i := first; i <= last; i++ {
+		bound := "    > 1000.00 ms"
+		if i < len(latencyBuckets) {
+			bound = fmt.Sprintf("  <= %8s ms", ms(latencyBuckets[i]))
+		}
+		share := float64(counts[i]) / float64(len(s.samples))
+		fmt.Fprintf(w, "%s %-40s %6.2f%% (%d)\n", bound, strings.Repeat("#", int(share*40+0.5)), share*100, counts[i])
+	}
+}
+
+// summary is the redis-cli --latency line.
+func (s *latencyStats) summary() string {
+	return fmt.Sprintf("min: %s, max: %s, avg: %s (%d samples)", ms(s.min), ms(s.max), ms(s.avg()), s.count())
+}
+
+// ms formats d in milliseconds.
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.2f", float64(d)/float64(time.Millisecond))
+}
+
+// runLatency sends PING in a loop and prints the latency, on one updated
+// line or, with --latency-history, a new line for every interval. Ctrl-C
+// prints the histogram of the last interval.
+func runLatency(options *cliOptions) int {
+
+	client, err := options.dial()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+		return 1
+	}
+	defer client.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	interval := time.Duration(options.interval * float64(time.Second))
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	stats, started := &latencyStats{}, time.Now()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			if output == outputTTY {
+				fmt.Println()
+			} else {
+				fmt.Println(stats.summary())
+			}
+			stats.printHistogram(os.Stdout)
+			return 0
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		if err = errorReply(client.Send("PING").Wait()); nil != err {
+			fmt.Fprintln(os.Stderr, "\nI/O error:", err)
+			return 1
+		}
+		stats.add(time.Since(start))
+
+		switch {
+		case options.latencyHistory && time.Since(started) >= interval:
+			if output == outputTTY {
+				fmt.Print("\r\x1b[0K")
+			}
+			fmt.Printf("%s -- %.2f seconds range\n", stats.summary(), time.Since(started).Seconds())
+			stats, started = &latencyStats{}, time.Now()
+		case output == outputTTY:
+			fmt.Printf("\r\x1b[0K%s", stats.summary())
+		}
+	}
+}
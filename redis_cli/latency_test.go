@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyStats_Percentile(t *testing.T) {
+
+	// ten samples of 1ms to 10ms, added out of order.
+	var ten latencyStats
+	for _, ms := range []int{7, 3, 10, 1, 9, 5, 2, 8, 4, 6} {
+		ten.add(time.Duration(ms) * time.Millisecond)
+	}
+	var one latencyStats
+	one.add(3 * time.Millisecond)
+
+	tests := []struct {
+		name  string
+		stats *latencyStats
+		p     float64
+		want  time.Duration
+	}{
+		{"empty", &latencyStats{}, 50, 0},
+		{"single-p0", &one, 0, 3 * time.Millisecond},
+		{"single-p50", &one, 50, 3 * time.Millisecond},
+		{"single-p100", &one, 100, 3 * time.Millisecond},
+		{"p0", &ten, 0, time.Millisecond},
+		{"p10", &ten, 10, time.Millisecond},
+		{"p50", &ten, 50, 5 * time.Millisecond},
+		{"p90", &ten, 90, 9 * time.Millisecond},
+		{"p99", &ten, 99, 10 * time.Millisecond},
+		{"p100", &ten, 100, 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := tt.stats.percentile(tt.p); got != tt.want {
+			t.Errorf("%s: percentile(%v) = %v, want %v", tt.name, tt.p, got, tt.want)
+		}
+	}
+
+	// samples added after sorting are sorted again.
+	ten.add(0)
+	if got := ten.percentile(0); got != 0 {
+		t.Errorf("percentile(0) after add = %v, want 0", got)
+	}
+	if ten.min != 0 || ten.max != 10*time.Millisecond || ten.count() != 11 {
+		t.Errorf("min, max, count = %v, %v, %d", ten.min, ten.max, ten.count())
+	}
+}
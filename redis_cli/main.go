@@ -17,33 +17,210 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/go-netty/go-netty"
-	"github.com/go-netty/go-netty/utils"
+	"github.com/go-netty/go-netty-samples/redis_cli/redis"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
 )
 
 func main() {
 
+	options := parseOptions()
+	output = options.outputMode()
+
+	if options.pipe {
+		os.Exit(runPipe(options))
+	}
+
+	if options.latency || options.latencyHistory {
+		os.Exit(runLatency(options))
+	}
+
+	switch {
+	case options.scan:
+		os.Exit(runScan(options))
+	case options.bigKeys || options.memKeys:
+		os.Exit(runBigKeys(options, options.memKeys))
+	}
+
+	if "" != options.eval {
+		os.Exit(runEval(options, flag.Args()))
+	}
+
+	if "benchmark" == strings.ToLower(flag.Arg(0)) {
+		os.Exit(runBenchmark(options, flag.Args()[1:]))
+	}
+
+	// trailing arguments are executed as a single command.
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(options, flag.Args()))
+	}
+
+	if options.cluster || "" != options.sentinels {
+		runClient(options)
+		return
+	}
+
+	config, err := options.tlsConfig()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// the console outlives connections, it is attached once connected.
+	console := newRedisConsole(options.addr(), options.handshake())
+
 	// setup client pipeline initializer.
 	setupCodec := func(channel netty.Channel) {
 		channel.Pipeline().
-			AddLast(&simpleRedisCodec{}, &simpleRedisConsole{})
+			AddLast(redis.NewCodec(), redis.NewPipelineHandler()).
+			// print messages published to subscribed channels.
+			AddLast(redis.NewPubSubHandler(console.printMessage)).
+			AddLast(console)
 	}
 
 	// new bootstrap
-	var bootstrap = netty.NewBootstrap(netty.WithClientInitializer(setupCodec))
+	bootstrapOptions := []netty.Option{netty.WithClientInitializer(setupCodec)}
+	if nil != config {
+		bootstrapOptions = append(bootstrapOptions, netty.WithTransport(redis.NewTLSTransport(config)))
+	}
+	var bootstrap = netty.NewBootstrap(bootstrapOptions...)
 
 	// connect to redis server
 	fmt.Println("connecting redis server ...")
 
-	ch, err := bootstrap.Connect("127.0.0.1:6379")
-	utils.Assert(err)
+	// a server that is down is retried like a dropped connection.
+	ch, err := bootstrap.Connect(options.addr())
+	if nil != err {
+		fmt.Fprintln(console.term, "Could not connect to Redis at", options.addr()+":", err)
+	}
+
+	// reconnect with backoff until the user leaves.
+	for attempt := 0; ; attempt++ {
+		if nil != ch {
+			select {
+			case <-ch.Context().Done():
+				attempt = 0
+			case <-console.Exited():
+			}
+		}
+
+		select {
+		case <-console.Exited():
+			fmt.Println("exited")
+			return
+		case <-time.After(redis.DefaultBackoff.Delay(attempt)):
+		}
+
+		if ch, err = bootstrap.Connect(options.addr()); nil != err {
+			fmt.Fprintln(console.term, "Could not connect to Redis at", options.addr()+":", err)
+		}
+	}
+}
+
+// runCommand executes args, prints the reply and returns the exit status,
+// error replies exit with 1.
+func runCommand(options *cliOptions, args []string) int {
+
+	client, err := options.dial()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+		return 1
+	}
+	defer client.Close()
+
+	command := make([]interface{}, len(args))
+	for i, arg := range args {
+		command[i] = arg
+	}
+
+	resp, err := client.Send(command...).Wait()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	printResp(os.Stdout, resp)
+	if resp.Kind == redisgo.ErrorKind || resp.Kind == redisgo.BlobErrorKind {
+		return 1
+	}
+	return 0
+}
+
+// runEval runs the Lua script named by --eval, args are its keys and, after
+// a "," argument, its arguments. The script is sent as EVALSHA first.
+func runEval(options *cliOptions, args []string) int {
+
+	src, err := os.ReadFile(options.eval)
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Can't open file:", err)
+		return 1
+	}
+
+	keys, argv := args, []interface{}{}
+	for i, arg := range args {
+		if "," == arg {
+			keys = args[:i]
+			for _, arg := range args[i+1:] {
+				argv = append(argv, arg)
+			}
+			break
+		}
+	}
+
+	client, err := options.dial()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+		return 1
+	}
+	defer client.Close()
+
+	resp, err := redis.NewScript(string(src)).Run(context.Background(), client, keys, argv...)
+	if e, ok := err.(redis.Error); ok {
+		resp, err = &redisgo.Resp{Kind: redisgo.ErrorKind, Data: string(e)}, nil
+	}
+	if nil != err {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	printResp(os.Stdout, resp)
+	if resp.Kind == redisgo.ErrorKind {
+		return 1
+	}
+	return 0
+}
+
+// runClient drives the console with a cluster or sentinel client.
+func runClient(options *cliOptions) {
+
+	fmt.Println("connecting redis server ...")
+
+	client, err := options.dial()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+		os.Exit(1)
+	}
 
-	select {
-	case <-ch.Context().Done():
-	case <-bootstrap.Context().Done():
+	fmt.Println("connected")
+
+	console := newRedisConsole(options.addr(), nil)
+
+	// follow the master elected by the sentinels.
+	if sentinel, ok := client.(*redis.SentinelClient); ok {
+		console.addr = sentinel.Addr()
+		sentinel.OnSwitch(func(addr string) {
+			fmt.Fprintln(console.term, "master switched to", addr)
+		})
 	}
 
+	console.attachConsole(client, func(error) { client.Close() })
+
 	fmt.Println("exited")
 }
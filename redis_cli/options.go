@@ -0,0 +1,215 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redis"
+)
+
+// cliOptions holds the command line flags, they follow redis-cli.
+type cliOptions struct {
+	host      string
+	port      int
+	user      string
+	password  string
+	db        int
+	tls       bool
+	cacert    string
+	cert      string
+	key       string
+	sni       string
+	insecure  bool
+	cluster   bool
+	sentinels string
+	master    string
+	raw       bool
+	noRaw     bool
+	csv       bool
+	json      bool
+
+	pipe        bool
+	pipeTimeout int
+
+	latency        bool
+	latencyHistory bool
+	interval       float64
+
+	scan    bool
+	pattern string
+	count   int
+	bigKeys bool
+	memKeys bool
+
+	eval string
+}
+
+func parseOptions() *cliOptions {
+	o := &cliOptions{}
+	flag.StringVar(&o.host, "h", "127.0.0.1", "server hostname")
+	flag.IntVar(&o.port, "p", 6379, "server port")
+	flag.StringVar(&o.user, "user", "", "username to send to AUTH")
+	flag.StringVar(&o.password, "a", "", "password to send to AUTH")
+	flag.IntVar(&o.db, "n", 0, "database number")
+	flag.BoolVar(&o.tls, "tls", false, "establish a secure TLS connection")
+	flag.StringVar(&o.cacert, "cacert", "", "CA certificate file to verify the server with")
+	flag.StringVar(&o.cert, "cert", "", "client certificate to authenticate with")
+	flag.StringVar(&o.key, "key", "", "private key file to authenticate with")
+	flag.StringVar(&o.sni, "sni", "", "server name indication for TLS")
+	flag.BoolVar(&o.insecure, "insecure", false, "allow insecure TLS connection by skipping cert validation")
+	flag.BoolVar(&o.cluster, "c", false, "enable cluster mode, follow -MOVED and -ASK redirections")
+	flag.StringVar(&o.sentinels, "sentinel", "", "comma separated sentinel addresses, connect to the master they report")
+	flag.StringVar(&o.master, "master", "mymaster", "name of the master monitored by the sentinels")
+	flag.BoolVar(&o.raw, "raw", false, "use raw formatting for replies, the default when stdout is not a tty")
+	flag.BoolVar(&o.noRaw, "no-raw", false, "force formatted output even when stdout is not a tty")
+	flag.BoolVar(&o.csv, "csv", false, "output in CSV format")
+	flag.BoolVar(&o.json, "json", false, "output in JSON format")
+	flag.BoolVar(&o.pipe, "pipe", false, "transfer raw RESP or one command per line from stdin to the server")
+	flag.IntVar(&o.pipeTimeout, "pipe-timeout", 30, "in --pipe mode, abort if no reply arrives within `n` seconds after sending all data, 0 waits forever")
+	flag.BoolVar(&o.latency, "latency", false, "enter a special mode continuously sampling latency")
+	flag.BoolVar(&o.latencyHistory, "latency-history", false, "like --latency but print a new line for every interval")
+	flag.Float64Var(&o.interval, "i", 0, "interval in `seconds` between --latency-history lines, 15 by default")
+	flag.BoolVar(&o.scan, "scan", false, "list all keys using the SCAN command")
+	flag.StringVar(&o.pattern, "pattern", "", "keys `pattern` when using the --scan, --bigkeys or --memkeys options")
+	flag.IntVar(&o.count, "count", 0, "`count` option when using the --scan, --bigkeys or --memkeys options")
+	flag.BoolVar(&o.bigKeys, "bigkeys", false, "sample keys looking for keys with many elements")
+	flag.BoolVar(&o.memKeys, "memkeys", false, "sample keys looking for keys consuming a lot of memory")
+	flag.StringVar(&o.eval, "eval", "", "send an EVAL command using the Lua script at `file`, keys and arguments are separated by ,")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [OPTIONS] [cmd [arg [arg ...]]]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [OPTIONS] --eval file [key [key ...]] [, arg [arg ...]]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [OPTIONS] benchmark [BENCHMARK OPTIONS]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	return o
+}
+
+// outputMode picks the reply format, raw by default when stdout is
+// redirected to a file or a pipe.
+func (o *cliOptions) outputMode() outputMode {
+	switch {
+	case o.json:
+		return outputJSON
+	case o.csv:
+		return outputCSV
+	case o.raw:
+		return outputRaw
+	case o.noRaw:
+		return outputTTY
+	}
+	if stat, err := os.Stdout.Stat(); nil == err && 0 == stat.Mode()&os.ModeCharDevice {
+		return outputRaw
+	}
+	return outputTTY
+}
+
+func (o *cliOptions) addr() string {
+	return net.JoinHostPort(o.host, strconv.Itoa(o.port))
+}
+
+// tlsConfig loads the certificates named by the TLS flags, it returns nil
+// if --tls is not set.
+func (o *cliOptions) tlsConfig() (*tls.Config, error) {
+	if !o.tls {
+		return nil, nil
+	}
+
+	config := &tls.Config{ServerName: o.sni, InsecureSkipVerify: o.insecure}
+
+	if "" != o.cacert {
+		pem, err := os.ReadFile(o.cacert)
+		if nil != err {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.cacert)
+		}
+	}
+
+	if "" != o.cert || "" != o.key {
+		cert, err := tls.LoadX509KeyPair(o.cert, o.key)
+		if nil != err {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// dialOptions converts the flags for the redis client library.
+func (o *cliOptions) dialOptions() ([]redis.DialOption, error) {
+	config, err := o.tlsConfig()
+	if nil != err {
+		return nil, err
+	}
+
+	options := []redis.DialOption{redis.WithAuth(o.user, o.password), redis.WithDB(o.db)}
+	if nil != config {
+		// the sentinels are reached over TLS as well, like redis-cli does.
+		options = append(options, redis.WithTLS(config), redis.WithSentinelOptions(redis.WithTLS(config)))
+	}
+	return options, nil
+}
+
+// handshake returns the commands the console sends once connected.
+func (o *cliOptions) handshake() [][]interface{} {
+	var commands [][]interface{}
+	switch {
+	case "" != o.user:
+		commands = append(commands, []interface{}{"AUTH", o.user, o.password})
+	case "" != o.password:
+		commands = append(commands, []interface{}{"AUTH", o.password})
+	}
+	if 0 != o.db {
+		commands = append(commands, []interface{}{"SELECT", o.db})
+	}
+	return commands
+}
+
+// commandClient is a library client the console can drive.
+type commandClient interface {
+	commandSender
+	redis.Doer
+	Close() error
+}
+
+// dial connects with the redis client library according to the mode.
+func (o *cliOptions) dial() (commandClient, error) {
+	options, err := o.dialOptions()
+	if nil != err {
+		return nil, err
+	}
+
+	switch {
+	case o.cluster:
+		return redis.DialCluster([]string{o.addr()}, options...)
+	case "" != o.sentinels:
+		return redis.DialSentinel(o.master, strings.Split(o.sentinels, ","), options...)
+	default:
+		return redis.Dial(o.addr(), options...)
+	}
+}
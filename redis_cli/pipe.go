@@ -0,0 +1,256 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redis"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// pipeChunk is the size of the writes in --pipe mode.
+const pipeChunk = 64 * 1024
+
+// runPipe writes stdin to the server without waiting for replies, either
+// raw RESP or one command per line, and returns the exit status. Replies
+// are only counted, the last one answers an ECHO of a random marker.
+func runPipe(options *cliOptions) int {
+
+	config, err := options.tlsConfig()
+	if nil != err {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	handshake := options.handshake()
+	writer := &pipeWriter{active: make(chan netty.HandlerContext, 1)}
+	counter := newPipeCounter(len(handshake))
+
+	// setup client pipeline initializer, the input is written below the codec.
+	setupCodec := func(channel netty.Channel) {
+		channel.Pipeline().AddLast(writer, redis.NewCodec(), counter)
+	}
+
+	bootstrapOptions := []netty.Option{netty.WithClientInitializer(setupCodec)}
+	if nil != config {
+		bootstrapOptions = append(bootstrapOptions, netty.WithTransport(redis.NewTLSTransport(config)))
+	}
+	bootstrap := netty.NewBootstrap(bootstrapOptions...)
+	defer bootstrap.Shutdown()
+
+	ch, err := bootstrap.Connect(options.addr())
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Could not connect to Redis at", options.addr()+":", err)
+		return 1
+	}
+	ctx := <-writer.active
+
+	write := func(p []byte) error {
+		if !ch.IsActive() {
+			return fmt.Errorf("connection lost: %v", ch.Context().Err())
+		}
+		ctx.Write(p)
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	for _, command := range handshake {
+		redisgo.EncodeMulti(&buffer, redis.Args(command...)...)
+	}
+	if buffer.Len() > 0 {
+		err = write(buffer.Bytes())
+	}
+	if nil == err {
+		err = copyPipe(write, bufio.NewReaderSize(os.Stdin, pipeChunk))
+	}
+	if nil == err {
+		buffer = bytes.Buffer{}
+		redisgo.EncodeMulti(&buffer, redis.Args("ECHO", counter.marker)...)
+		err = write(buffer.Bytes())
+	}
+	if nil != err {
+		fmt.Fprintln(os.Stderr, "Error writing to the server:", err)
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "All data transferred. Waiting for the last reply...")
+
+	timeout := time.Duration(options.pipeTimeout) * time.Second
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for waiting := true; waiting; {
+		select {
+		case <-counter.last:
+			fmt.Fprintln(os.Stderr, "Last reply received from server.")
+			waiting = false
+		case <-ch.Context().Done():
+			fmt.Fprintln(os.Stderr, "Error reading from the server: connection closed")
+			return 1
+		case <-ticker.C:
+			if timeout > 0 && counter.idle() > timeout {
+				fmt.Fprintf(os.Stderr, "No replies for %d seconds: exiting.\n", options.pipeTimeout)
+				return 1
+			}
+		}
+	}
+
+	errors, replies := atomic.LoadInt64(&counter.errors), atomic.LoadInt64(&counter.replies)
+	fmt.Printf("errors: %d, replies: %d\n", errors, replies)
+	if errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// copyPipe passes raw RESP through as it is and encodes anything else as
+// one command per line, quoted like console input.
+func copyPipe(write func(p []byte) error, reader *bufio.Reader) error {
+
+	if first, err := reader.Peek(1); nil != err {
+		return nil
+	} else if '*' == first[0] {
+		for {
+			chunk := make([]byte, pipeChunk)
+			n, err := reader.Read(chunk)
+			if n > 0 {
+				if err := write(chunk[:n]); nil != err {
+					return err
+				}
+			}
+			if io.EOF == err {
+				return nil
+			}
+			if nil != err {
+				return err
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, pipeChunk), 512*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if "" == text {
+			continue
+		}
+
+		args, err := redisgo.SplitArgs(text)
+		if nil != err {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		values := make([]redisgo.Value, len(args))
+		for i, arg := range args {
+			values[i] = redisgo.BlukString(arg)
+		}
+		redisgo.EncodeMulti(&buffer, values...)
+
+		// hand every chunk over as a new slice, the write may be queued.
+		if buffer.Len() >= pipeChunk {
+			if err = write(buffer.Bytes()); nil != err {
+				return err
+			}
+			buffer = bytes.Buffer{}
+		}
+	}
+	if nil != scanner.Err() {
+		return scanner.Err()
+	}
+	if buffer.Len() > 0 {
+		return write(buffer.Bytes())
+	}
+	return nil
+}
+
+// pipeWriter sits below the codec, so that bytes written through its
+// context go out unchanged.
+type pipeWriter struct {
+	active chan netty.HandlerContext
+}
+
+func (w *pipeWriter) HandleActive(ctx netty.ActiveContext) {
+	w.active <- ctx
+	ctx.HandleActive()
+}
+
+// pipeCounter counts replies and error replies, error replies are printed
+// to stderr as they arrive.
+type pipeCounter struct {
+	// replies to the handshake are not counted, a failure aborts.
+	handshake int
+
+	marker    string
+	last      chan struct{}
+	replies   int64
+	errors    int64
+	lastReply int64
+}
+
+func newPipeCounter(handshake int) *pipeCounter {
+	var random [20]byte
+	rand.Read(random[:])
+	return &pipeCounter{
+		handshake: handshake,
+		marker:    hex.EncodeToString(random[:]),
+		last:      make(chan struct{}),
+		lastReply: time.Now().UnixNano(),
+	}
+}
+
+func (p *pipeCounter) HandleRead(ctx netty.InboundContext, message netty.Message) {
+
+	resp := message.(*redisgo.Resp)
+	atomic.StoreInt64(&p.lastReply, time.Now().UnixNano())
+
+	failed := resp.Kind == redisgo.ErrorKind || resp.Kind == redisgo.BlobErrorKind
+	if p.handshake > 0 {
+		p.handshake--
+		if failed {
+			fmt.Fprintln(os.Stderr, resp.Data)
+			ctx.Close(redis.Error(resp.Data))
+		}
+		return
+	}
+
+	if resp.Kind == redisgo.BlukKind && resp.Data == p.marker {
+		close(p.last)
+		return
+	}
+
+	atomic.AddInt64(&p.replies, 1)
+	if failed {
+		atomic.AddInt64(&p.errors, 1)
+		fmt.Fprintln(os.Stderr, resp.Data)
+	}
+}
+
+// idle returns the time since the last reply.
+func (p *pipeCounter) idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&p.lastReply)))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+func TestCopyPipe(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", "", false},
+		{"raw", "*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n", "*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n", false},
+		{"raw-unchecked", "*1\r\n+not resp", "*1\r\n+not resp", false},
+		{"inline", "PING\nSET k \"a b\"\n", "*1\r\n$4\r\nPING\r\n*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$3\r\na b\r\n", false},
+		{"inline-crlf", "PING\r\n\r\n  \r\nECHO 'x\\ny'", "*1\r\n$4\r\nPING\r\n*2\r\n$4\r\nECHO\r\n$4\r\nx\\ny\r\n", false},
+		{"inline-quoting", "PING\nSET k \"a\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			write := func(p []byte) error {
+				got.Write(p)
+				return nil
+			}
+			err := copyPipe(write, bufio.NewReader(strings.NewReader(tt.input)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("copyPipe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.HasPrefix(err.Error(), "line 2:") {
+					t.Errorf("copyPipe() error = %v, want line 2", err)
+				}
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("copyPipe() wrote %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeCounter(t *testing.T) {
+
+	// one handshake reply, then the replies to the input and the marker.
+	counter := newPipeCounter(1)
+	for _, resp := range []*redisgo.Resp{
+		{Kind: redisgo.SimpleKind, Data: "OK"},
+		{Kind: redisgo.SimpleKind, Data: "OK"},
+		{Kind: redisgo.ErrorKind, Data: "ERR wrong number of arguments"},
+		{Kind: redisgo.IntegerKind, Data: "1"},
+		{Kind: redisgo.BlobErrorKind, Data: "SYNTAX invalid"},
+		{Kind: redisgo.BlukKind, Data: counter.marker},
+	} {
+		counter.HandleRead(nil, resp)
+	}
+
+	select {
+	case <-counter.last:
+	default:
+		t.Fatal("last reply not received")
+	}
+	if counter.replies != 4 || counter.errors != 2 {
+		t.Errorf("replies, errors = %d, %d, want 4, 2", counter.replies, counter.errors)
+	}
+}
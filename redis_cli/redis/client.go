@@ -0,0 +1,395 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// Client is a redis connection served by a netty pipeline, it is safe for
+// concurrent use and pipelines requests sent from many goroutines.
+type Client struct {
+	addr        string
+	options     dialOptions
+	dialOptions []DialOption
+	bootstrap   netty.Bootstrap
+	pubsub      *PubSubHandler
+	tracking    *TrackingHandler
+	done        chan struct{}
+
+	// held while writing, so that a transaction goes out in one piece.
+	writeMutex sync.Mutex
+
+	mutex    sync.Mutex
+	channel  netty.Channel
+	pipeline *PipelineHandler
+	closed   bool
+
+	// CLIENT TRACKING command sent again after reconnecting.
+	track []interface{}
+
+	// reconnect state, requests sent while reconnecting are queued and
+	// fail with failed once reconnecting gave up.
+	reconnecting bool
+	queued       []*Request
+	failed       error
+}
+
+// DialOption configures a connection made by Dial.
+type DialOption func(options *dialOptions)
+
+type dialOptions struct {
+	username  string
+	password  string
+	db        int
+	protocol  int
+	name      string
+	tlsConfig *tls.Config
+	backoff   *Backoff
+	retry     RetryPolicy
+	codec     []CodecOption
+	inactive  func(client *Client)
+	sentinel  []DialOption
+}
+
+// WithAuth sends AUTH once connected, username may be empty for servers
+// without ACL users.
+func WithAuth(username, password string) DialOption {
+	return func(options *dialOptions) {
+		options.username, options.password = username, password
+	}
+}
+
+// WithDB selects the logical database db once connected.
+func WithDB(db int) DialOption {
+	return func(options *dialOptions) {
+		options.db = db
+	}
+}
+
+// WithProtocol switches the connection to RESP protocol with HELLO, RESP3
+// is needed for invalidation pushes of client-side caching.
+func WithProtocol(protocol int) DialOption {
+	return func(options *dialOptions) {
+		options.protocol = protocol
+	}
+}
+
+// WithClientName names the connection with CLIENT SETNAME once connected.
+func WithClientName(name string) DialOption {
+	return func(options *dialOptions) {
+		options.name = name
+	}
+}
+
+// WithTLS connects over TLS with config.
+func WithTLS(config *tls.Config) DialOption {
+	return func(options *dialOptions) {
+		options.tlsConfig = config
+	}
+}
+
+// WithCodecOptions configures the codec of the connection, such as the
+// limits of the replies it accepts.
+func WithCodecOptions(options ...CodecOption) DialOption {
+	return func(o *dialOptions) {
+		o.codec = options
+	}
+}
+
+// WithSentinelOptions configures the connections DialSentinel makes to the
+// sentinels themselves, such as WithAuth with the sentinel password or
+// WithTLS. The other options only apply to the master.
+func WithSentinelOptions(options ...DialOption) DialOption {
+	return func(o *dialOptions) {
+		o.sentinel = options
+	}
+}
+
+// handshake returns the commands sent before the connection is handed out.
+func (o *dialOptions) handshake() [][]interface{} {
+	var commands [][]interface{}
+	switch {
+	case "" != o.username:
+		commands = append(commands, []interface{}{"AUTH", o.username, o.password})
+	case "" != o.password:
+		commands = append(commands, []interface{}{"AUTH", o.password})
+	}
+	if 0 != o.protocol {
+		commands = append(commands, []interface{}{"HELLO", o.protocol})
+	}
+	if 0 != o.db {
+		commands = append(commands, []interface{}{"SELECT", o.db})
+	}
+	if "" != o.name {
+		commands = append(commands, []interface{}{"CLIENT", "SETNAME", o.name})
+	}
+	return commands
+}
+
+// Dial connects to the redis server at addr, the first connection is not
+// retried even with WithReconnect.
+func Dial(addr string, options ...DialOption) (*Client, error) {
+
+	c := &Client{addr: addr, dialOptions: options, pubsub: NewPubSubHandler(nil), tracking: NewTrackingHandler(nil), done: make(chan struct{})}
+	c.options.retry = NeverRetry
+	for _, option := range options {
+		option(&c.options)
+	}
+
+	// setup client pipeline initializer.
+	setupCodec := func(channel netty.Channel) {
+		pipeline := NewPipelineHandler()
+		channel.Pipeline().AddLast(NewCodec(c.options.codec...), pipeline, c.tracking, c.pubsub)
+		if nil != c.options.backoff {
+			channel.Pipeline().AddLast(&reconnectHandler{client: c, pipeline: pipeline})
+		}
+		if nil != c.options.inactive {
+			channel.Pipeline().AddLast(&inactiveHandler{client: c, fn: c.options.inactive})
+		}
+
+		c.mutex.Lock()
+		c.pipeline = pipeline
+		c.mutex.Unlock()
+	}
+
+	bootstrapOptions := []netty.Option{netty.WithClientInitializer(setupCodec)}
+	if nil != c.options.tlsConfig {
+		bootstrapOptions = append(bootstrapOptions, netty.WithTransport(NewTLSTransport(c.options.tlsConfig)))
+	}
+	c.bootstrap = netty.NewBootstrap(bootstrapOptions...)
+
+	// nothing to reconnect before the first connection is set up.
+	c.reconnecting = true
+	ch, err := c.connect(nil, nil)
+	if nil == err {
+		if err = c.attach(ch); nil != err {
+			ch.Close(err)
+		}
+	}
+	if nil != err {
+		c.bootstrap.Shutdown()
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect dials a new channel and sends the handshake, followed by the
+// subscriptions to restore.
+func (c *Client) connect(channels, patterns []string) (netty.Channel, error) {
+
+	ch, err := c.bootstrap.Connect(c.addr)
+	if nil != err {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	pipeline, track := c.pipeline, c.track
+	c.mutex.Unlock()
+
+	commands := c.options.handshake()
+	if nil != track {
+		commands = append(commands, track)
+	}
+	if len(channels) > 0 {
+		commands = append(commands, append([]interface{}{"SUBSCRIBE"}, strings2args(channels)...))
+	}
+	if len(patterns) > 0 {
+		commands = append(commands, append([]interface{}{"PSUBSCRIBE"}, strings2args(patterns)...))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, command := range commands {
+		request := NewRequest(Args(command...)...)
+		if err = ch.Write(request); nil != err {
+			request.Future.resolve(nil, err)
+		}
+		if _, err = wait(ctx, request.Future); nil != err {
+			ch.Close(err)
+			return nil, fmt.Errorf("redis: %s: %w", command[0], err)
+		}
+	}
+
+	// the handshake itself is never retried.
+	if nil != c.options.backoff {
+		pipeline.mutex.Lock()
+		pipeline.Retry = c.retry
+		pipeline.mutex.Unlock()
+	}
+	return ch, nil
+}
+
+// Channel returns the underlying netty channel, it changes when the
+// client reconnects.
+func (c *Client) Channel() netty.Channel {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.channel
+}
+
+// Close closes the connection, pending requests fail with ErrClosed.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.closed = true
+	ch, queued := c.channel, c.queued
+	c.queued = nil
+	c.mutex.Unlock()
+
+	close(c.done)
+	for _, request := range queued {
+		request.Future.resolve(nil, ErrClosed)
+	}
+	if nil != ch {
+		ch.Close(ErrClosed)
+	}
+	c.bootstrap.Shutdown()
+	return nil
+}
+
+// Send writes a command without waiting for the reply. While the client
+// reconnects the command is held back until the connection is up again.
+func (c *Client) Send(args ...interface{}) *Future {
+	request := NewRequest(Args(args...)...)
+	c.sendBatch(request)
+	return request.Future
+}
+
+// sendBatch writes requests back to back, nothing else is written in
+// between. While reconnecting they are held back together.
+func (c *Client) sendBatch(requests ...*Request) {
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.mutex.Lock()
+	if c.reconnecting && !c.closed {
+		c.queued = append(c.queued, requests...)
+		c.mutex.Unlock()
+		return
+	}
+	ch, failed := c.channel, c.failed
+	if c.closed {
+		failed = ErrClosed
+	}
+	c.mutex.Unlock()
+
+	for _, request := range requests {
+		if nil != failed {
+			request.Future.resolve(nil, failed)
+			continue
+		}
+
+		if err := ch.Write(request); nil != err {
+			// the channel dropped before the pipeline saw the request.
+			if nil == c.options.backoff || !c.retry(request, false) {
+				request.Future.resolve(nil, err)
+			}
+		}
+	}
+}
+
+// Do sends a command and waits for its reply, error replies are returned
+// as Error.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (*redisgo.Resp, error) {
+	return wait(ctx, c.Send(args...))
+}
+
+func wait(ctx context.Context, future *Future) (*redisgo.Resp, error) {
+	select {
+	case <-future.Done():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	resp, err := future.Wait()
+	if nil != err {
+		return nil, err
+	}
+	if err = replyError(resp); nil != err {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Args converts Go values to command arguments, everything is sent as a
+// bulk string as redis expects.
+func Args(args ...interface{}) []redisgo.Value {
+	values := make([]redisgo.Value, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg2value(arg))
+	}
+	return values
+}
+
+func arg2value(arg interface{}) redisgo.Value {
+	switch v := arg.(type) {
+	case redisgo.Value:
+		// redisgo.Int(5) would go out as :5, which redis does not accept.
+		return redisgo.BlukString(v.String())
+	case string:
+		return redisgo.BlukString(v)
+	case []byte:
+		return redisgo.Bluk(v)
+	case int:
+		return redisgo.BlukString(strconv.Itoa(v))
+	case int8:
+		return redisgo.BlukString(strconv.FormatInt(int64(v), 10))
+	case int16:
+		return redisgo.BlukString(strconv.FormatInt(int64(v), 10))
+	case int32:
+		return redisgo.BlukString(strconv.FormatInt(int64(v), 10))
+	case int64:
+		return redisgo.BlukString(strconv.FormatInt(v, 10))
+	case uint:
+		return redisgo.BlukString(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		return redisgo.BlukString(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		return redisgo.BlukString(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		return redisgo.BlukString(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		return redisgo.BlukString(strconv.FormatUint(v, 10))
+	case float32:
+		return redisgo.BlukString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return redisgo.BlukString(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		if v {
+			return redisgo.BlukString("1")
+		}
+		return redisgo.BlukString("0")
+	case nil:
+		return redisgo.BlukString("")
+	default:
+		return redisgo.BlukString(fmt.Sprint(v))
+	}
+}
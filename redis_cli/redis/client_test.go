@@ -0,0 +1,356 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+	"github.com/go-netty/go-netty/transport"
+	"github.com/go-netty/go-netty/utils"
+)
+
+// testServer answers each command with the raw RESP returned by serve.
+type testServer struct {
+	addr      string
+	bootstrap netty.Bootstrap
+	mutex     sync.Mutex
+	commands  []string
+	serve     func(args []string) string
+}
+
+func newTestServer(t *testing.T, serve func(args []string) string, options ...netty.Option) *testServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := &testServer{addr: addr, serve: serve}
+	s.bootstrap = netty.NewBootstrap(append(options, netty.WithChildInitializer(func(channel netty.Channel) {
+		channel.Pipeline().AddLast(&testServerCodec{decoder: redisgo.NewStreamDecoder(1024)}, s)
+	}))...)
+	s.bootstrap.Listen(addr).Async(func(error) {})
+	t.Cleanup(s.bootstrap.Shutdown)
+
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); nil == err {
+			conn.Close()
+			return s
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("test server not listening")
+	return nil
+}
+
+func (s *testServer) HandleRead(ctx netty.InboundContext, message netty.Message) {
+	args := message.([]string)
+	s.mutex.Lock()
+	s.commands = append(s.commands, strings.Join(args, " "))
+	s.mutex.Unlock()
+	ctx.Write(s.serve(args))
+}
+
+func (s *testServer) lastCommand() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.commands) == 0 {
+		return ""
+	}
+	return s.commands[len(s.commands)-1]
+}
+
+func (s *testServer) served(command string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, c := range s.commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+type testServerCodec struct {
+	decoder *redisgo.StreamDecoder
+}
+
+func (c *testServerCodec) HandleRead(ctx netty.InboundContext, message netty.Message) {
+	buffer := make([]byte, 1024)
+	n, err := message.(transport.Transport).Read(buffer)
+	if nil != err {
+		ctx.Close(err)
+		return
+	}
+	resps, err := c.decoder.Feed(buffer[:n])
+	utils.Assert(err)
+	for _, resp := range resps {
+		args := make([]string, len(resp.Array))
+		for i := range resp.Array {
+			args[i] = resp.Array[i].Data
+		}
+		ctx.HandleRead(args)
+	}
+}
+
+func (c *testServerCodec) HandleWrite(ctx netty.OutboundContext, message netty.Message) {
+	ctx.HandleWrite(bytes.NewBufferString(message.(string)))
+}
+
+func TestClient(t *testing.T) {
+
+	replies := map[string]string{
+		"GET":     "$5\r\nvalue\r\n",
+		"SET":     "+OK\r\n",
+		"DEL":     ":2\r\n",
+		"INCR":    ":11\r\n",
+		"EXPIRE":  ":1\r\n",
+		"PEXPIRE": ":1\r\n",
+		"HGETALL": "%2\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n",
+		"LPUSH":   ":3\r\n",
+		"ZRANGE":  "*4\r\n$1\r\nx\r\n$3\r\n1.5\r\n$1\r\ny\r\n$3\r\ninf\r\n",
+	}
+	server := newTestServer(t, func(args []string) string {
+		if reply, ok := replies[args[0]]; ok {
+			return reply
+		}
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	})
+
+	client, err := Dial(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		call    func() (interface{}, error)
+		command string
+		want    interface{}
+	}{
+		{"get", func() (interface{}, error) { return client.Get(ctx, "k") }, "GET k", "value"},
+		{"set", func() (interface{}, error) {
+			return client.Set(ctx, "k", 1, WithExpiration(1500*time.Millisecond), OnlyIfNotExists())
+		}, "SET k 1 PX 1500 NX", true},
+		{"del", func() (interface{}, error) { return client.Del(ctx, "a", "b") }, "DEL a b", int64(2)},
+		{"incr", func() (interface{}, error) { return client.Incr(ctx, "n") }, "INCR n", int64(11)},
+		{"expire", func() (interface{}, error) { return client.Expire(ctx, "k", time.Minute) }, "EXPIRE k 60", true},
+		{"pexpire", func() (interface{}, error) { return client.Expire(ctx, "k", 500*time.Millisecond) }, "PEXPIRE k 500", true},
+		{"set-sub-millisecond", func() (interface{}, error) {
+			return client.Set(ctx, "k", redisgo.Int(5), WithExpiration(time.Microsecond))
+		}, "SET k 5 PX 1", true},
+		{"hgetall", func() (interface{}, error) { return client.HGetAll(ctx, "h") }, "HGETALL h", map[string]string{"a": "1", "b": "2"}},
+		{"lpush", func() (interface{}, error) { return client.LPush(ctx, "l", "x", 2) }, "LPUSH l x 2", int64(3)},
+		{"zrange", func() (interface{}, error) { return client.ZRangeWithScores(ctx, "z", 0, -1) }, "ZRANGE z 0 -1 WITHSCORES", []Z{{"x", 1.5}, {"y", math.Inf(1)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if nil != err {
+				t.Fatalf("error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if command := server.lastCommand(); command != tt.command {
+				t.Errorf("command = %q, want %q", command, tt.command)
+			}
+		})
+	}
+
+	if _, err := client.Expire(ctx, "k", 0); nil == err {
+		t.Error("Expire(0) error = nil, want an invalid expire time")
+	}
+
+	_, err = client.Do(ctx, "FOO")
+	if e, ok := err.(Error); !ok || e.Prefix() != "ERR" {
+		t.Errorf("Do() error = %#v, want Error", err)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	if err := redisgo.EncodeMulti(buffer, Args("SET", redisgo.Int(5), 1.5, true, nil)...); nil != err {
+		t.Fatal(err)
+	}
+	// every argument is a bulk string, redis refuses anything else.
+	if got, want := buffer.String(), "*5\r\n$3\r\nSET\r\n$1\r\n5\r\n$3\r\n1.5\r\n$1\r\n1\r\n$0\r\n\r\n"; got != want {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+}
+
+func TestClient_Pipelining(t *testing.T) {
+
+	server := newTestServer(t, func(args []string) string {
+		return "$" + string(rune('0'+len(args[1]))) + "\r\n" + args[1] + "\r\n"
+	})
+
+	client, err := Dial(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var futures []*Future
+	for i := 0; i < 500; i++ {
+		futures = append(futures, client.Send("ECHO", i%10))
+	}
+	for i, future := range futures {
+		resp, err := future.Wait()
+		if nil != err {
+			t.Fatal(err)
+		}
+		if want := string(rune('0' + i%10)); resp.Data != want {
+			t.Fatalf("reply %d = %q, want %q", i, resp.Data, want)
+		}
+	}
+
+	client.Close()
+	if _, err := client.Send("PING").Wait(); nil == err {
+		t.Errorf("Send() after Close() expect error")
+	}
+}
+
+func TestDial_Handshake(t *testing.T) {
+
+	server := newTestServer(t, func(args []string) string {
+		if args[0] == "AUTH" && args[len(args)-1] != "secret" {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+		return "+OK\r\n"
+	})
+
+	client, err := Dial(server.addr, WithAuth("admin", "secret"), WithDB(2))
+	if nil != err {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	want := []string{"AUTH admin secret", "SELECT 2"}
+	server.mutex.Lock()
+	if !reflect.DeepEqual(server.commands, want) {
+		t.Errorf("handshake = %q, want %q", server.commands, want)
+	}
+	server.mutex.Unlock()
+
+	var e Error
+	_, err = Dial(server.addr, WithAuth("", "wrong"))
+	if !errors.As(err, &e) || e.Prefix() != "WRONGPASS" {
+		t.Errorf("Dial() error = %v, want WRONGPASS", err)
+	}
+}
+
+func TestClient_Reconnect(t *testing.T) {
+
+	var mutex sync.Mutex
+	stalled := map[string]bool{}
+	server := newTestServer(t, func(args []string) string {
+		switch args[0] {
+		case "SUBSCRIBE":
+			return "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n"
+		case "GET", "SET":
+			// the first one of each is left unanswered.
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !stalled[args[0]] {
+				stalled[args[0]] = true
+				return ""
+			}
+			return "$5\r\nvalue\r\n"
+		}
+		return "+OK\r\n"
+	})
+
+	backoff := Backoff{Min: 10 * time.Millisecond, Max: 50 * time.Millisecond, MaxAttempts: 3}
+	client, err := Dial(server.addr, WithAuth("", "secret"), WithDB(1), WithClientName("worker"),
+		WithReconnect(backoff), WithRetryPolicy(RetryIdempotent))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err = client.Subscribe(ctx, func(*Message) {}, "news"); nil != err {
+		t.Fatal(err)
+	}
+
+	get, set := client.Send("GET", "k"), client.Send("SET", "k", "v")
+	for !server.served("SET k v") {
+		time.Sleep(time.Millisecond)
+	}
+	dropped := errors.New("dropped")
+	client.Channel().Close(dropped)
+
+	// the read is sent again, the write may have been applied already.
+	if resp, err := wait(ctx, get); nil != err || resp.Data != "value" {
+		t.Errorf("GET = %v, %v, want value", resp, err)
+	}
+	if _, err := set.Wait(); err != dropped {
+		t.Errorf("SET error = %v, want %v", err, dropped)
+	}
+	if _, err = client.Do(ctx, "PING"); nil != err {
+		t.Fatal(err)
+	}
+
+	var handshakes, subscribes int
+	server.mutex.Lock()
+	for _, command := range server.commands {
+		switch command {
+		case "AUTH secret", "SELECT 1", "CLIENT SETNAME worker":
+			handshakes++
+		case "SUBSCRIBE news":
+			subscribes++
+		}
+	}
+	server.mutex.Unlock()
+	if handshakes != 6 || subscribes != 2 {
+		t.Errorf("handshake commands = %d, subscribes = %d, want 6 and 2", handshakes, subscribes)
+	}
+
+	// without a server to reconnect to, the client gives up.
+	server.bootstrap.Shutdown()
+	client.Channel().Close(dropped)
+	if _, err = client.Do(ctx, "PING"); nil == err || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Do() error = %v, want reconnect failure", err)
+	}
+}
+
+func TestBackoff_Delay(t *testing.T) {
+
+	backoff := Backoff{Min: 100 * time.Millisecond, Max: time.Second, Factor: 2}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if got := backoff.Delay(attempt); got != want*time.Millisecond {
+			t.Errorf("Delay(%d) = %v, want %v", attempt, got, want*time.Millisecond)
+		}
+	}
+
+	backoff.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := backoff.Delay(1); got < 100*time.Millisecond || got > 300*time.Millisecond {
+			t.Fatalf("Delay(1) = %v, want within 50%% of 200ms", got)
+		}
+	}
+
+	backoff.Jitter = 5
+	for i := 0; i < 100; i++ {
+		if got := backoff.Delay(1); got < 0 || got > 400*time.Millisecond {
+			t.Fatalf("Delay(1) = %v, want within 100%% of 200ms", got)
+		}
+	}
+}
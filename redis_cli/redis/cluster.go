@@ -0,0 +1,432 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// maxRedirects bounds how many MOVED/ASK redirects one command follows.
+const maxRedirects = 16
+
+// ErrNoNodes is returned when no cluster node could be reached.
+var ErrNoNodes = errors.New("redis: no cluster nodes reachable")
+
+// ClusterClient routes commands to the redis cluster node serving the
+// hash slot of their key, keeping one netty channel per node.
+type ClusterClient struct {
+	seeds      []string
+	options    []DialOption
+	mutex      sync.RWMutex
+	slots      [SlotCount]string // slot - master address
+	nodes      map[string]*Client
+	refreshing int32
+}
+
+// DialCluster connects to the cluster through the seed nodes and loads
+// the slot map, options apply to every node connection.
+func DialCluster(seeds []string, options ...DialOption) (*ClusterClient, error) {
+	c := &ClusterClient{seeds: seeds, options: options, nodes: make(map[string]*Client)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.Refresh(ctx); nil != err {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the connections to every node.
+func (c *ClusterClient) Close() error {
+	c.mutex.Lock()
+	nodes := c.nodes
+	c.nodes = make(map[string]*Client)
+	c.mutex.Unlock()
+
+	for _, node := range nodes {
+		node.Close()
+	}
+	return nil
+}
+
+// Nodes returns the addresses of the connected nodes.
+func (c *ClusterClient) Nodes() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	addrs := make([]string, 0, len(c.nodes))
+	for addr := range c.nodes {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// SlotAddr returns the address of the master serving slot, or "" if unknown.
+func (c *ClusterClient) SlotAddr(slot int) string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.slots[slot]
+}
+
+// node returns the connection to addr, dialing it on first use or once
+// the previous connection dropped.
+func (c *ClusterClient) node(addr string) (*Client, error) {
+	c.mutex.RLock()
+	node, ok := c.nodes[addr]
+	c.mutex.RUnlock()
+	if ok && node.Channel().IsActive() {
+		return node, nil
+	}
+
+	// dial without the lock, an unreachable node must not hold up the
+	// commands routed to the others.
+	dialed, err := Dial(addr, c.options...)
+	if nil != err {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	if node, ok = c.nodes[addr]; ok && node.Channel().IsActive() {
+		// another caller dialed it meanwhile.
+		c.mutex.Unlock()
+		dialed.Close()
+		return node, nil
+	}
+	c.nodes[addr] = dialed
+	c.mutex.Unlock()
+
+	// the dropped client still holds its bootstrap.
+	if ok {
+		node.Close()
+	}
+	return dialed, nil
+}
+
+// Refresh reloads the slot map from the first node that answers, trying
+// CLUSTER SHARDS before falling back to CLUSTER SLOTS.
+func (c *ClusterClient) Refresh(ctx context.Context) error {
+
+	addrs := append(c.Nodes(), c.seeds...)
+
+	err := ErrNoNodes
+	for _, addr := range addrs {
+		var node *Client
+		if node, err = c.node(addr); nil != err {
+			continue
+		}
+
+		var slots []slotRange
+		if slots, err = fetchShards(ctx, node, addr); nil != err {
+			if slots, err = fetchSlots(ctx, node, addr); nil != err {
+				continue
+			}
+		}
+
+		// slots left out of the reply have no owner any more.
+		var table [SlotCount]string
+		for _, r := range slots {
+			for slot := r.start; slot <= r.end && slot < SlotCount; slot++ {
+				table[slot] = r.addr
+			}
+		}
+		c.mutex.Lock()
+		c.slots = table
+		c.mutex.Unlock()
+		return nil
+	}
+	return err
+}
+
+// refreshAsync reloads the slot map in the background, at most once at a time.
+func (c *ClusterClient) refreshAsync() {
+	if atomic.CompareAndSwapInt32(&c.refreshing, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&c.refreshing, 0)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			c.Refresh(ctx)
+		}()
+	}
+}
+
+// Send routes a command without waiting for the reply, redirects are
+// followed in the background.
+func (c *ClusterClient) Send(args ...interface{}) *Future {
+	future := newFuture()
+	go func() {
+		future.resolve(c.do(context.Background(), args))
+	}()
+	return future
+}
+
+// Do routes a command to the node serving its key and waits for the
+// reply, following MOVED and ASK redirects.
+func (c *ClusterClient) Do(ctx context.Context, args ...interface{}) (*redisgo.Resp, error) {
+	resp, err := c.do(ctx, args)
+	if nil != err {
+		return nil, err
+	}
+	if err = replyError(resp); nil != err {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ClusterClient) do(ctx context.Context, args []interface{}) (*redisgo.Resp, error) {
+
+	values := Args(args...)
+
+	var addr string
+	if key, ok := commandKey(values); ok {
+		addr = c.SlotAddr(Slot(key))
+	}
+
+	asking := false
+	for i := 0; i < maxRedirects; i++ {
+		if "" == addr {
+			if addr = c.anyAddr(); "" == addr {
+				return nil, ErrNoNodes
+			}
+		}
+
+		node, err := c.node(addr)
+		if nil != err {
+			c.refreshAsync()
+			return nil, err
+		}
+
+		request := NewRequest(values...)
+		if asking {
+			// the target only serves a migrating slot right after ASKING,
+			// nothing else may be written in between.
+			ask := NewRequest(redisgo.BlukString("ASKING"))
+			node.sendBatch(ask, request)
+			if _, err = wait(ctx, ask.Future); nil != err {
+				return nil, c.failed(ctx, err)
+			}
+		} else {
+			node.sendBatch(request)
+		}
+
+		resp, err := waitRaw(ctx, request.Future)
+		if nil != err {
+			return nil, c.failed(ctx, err)
+		}
+
+		kind, slot, target, ok := parseRedirect(resp)
+		if !ok {
+			return resp, nil
+		}
+
+		switch kind {
+		case "MOVED":
+			// the slot has a new owner, the rest of the map may be stale too.
+			c.mutex.Lock()
+			c.slots[slot] = target
+			c.mutex.Unlock()
+			c.refreshAsync()
+			asking = false
+		case "ASK":
+			asking = true
+		}
+		addr = target
+	}
+	return nil, fmt.Errorf("redis: too many cluster redirects")
+}
+
+// failed returns err, the slot map is reloaded if the node could not be
+// reached rather than refused ASKING or ran out of time.
+func (c *ClusterClient) failed(ctx context.Context, err error) error {
+	var replyErr Error
+	if nil == ctx.Err() && !errors.As(err, &replyErr) {
+		c.refreshAsync()
+	}
+	return err
+}
+
+func waitRaw(ctx context.Context, future *Future) (*redisgo.Resp, error) {
+	select {
+	case <-future.Done():
+		return future.Wait()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *ClusterClient) anyAddr() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for addr, node := range c.nodes {
+		if node.Channel().IsActive() {
+			return addr
+		}
+	}
+	if len(c.seeds) > 0 {
+		return c.seeds[0]
+	}
+	return ""
+}
+
+// parseRedirect parses "MOVED 3999 127.0.0.1:6381" and "ASK 3999 127.0.0.1:6381" errors.
+func parseRedirect(resp *redisgo.Resp) (kind string, slot int, addr string, ok bool) {
+	if resp.Kind != redisgo.ErrorKind {
+		return
+	}
+	fields := strings.Fields(resp.Data)
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return
+	}
+	slot, err := strconv.Atoi(fields[1])
+	if nil != err || slot < 0 || slot >= SlotCount {
+		return
+	}
+	return fields[0], slot, fields[2], true
+}
+
+// commandKey returns the key that decides the slot of a command.
+func commandKey(args []redisgo.Value) (string, bool) {
+	if len(args) < 2 {
+		return "", false
+	}
+	switch strings.ToLower(args[0].String()) {
+	case "ping", "echo", "info", "auth", "hello", "select", "quit", "client", "cluster", "config",
+		"command", "dbsize", "flushdb", "flushall", "keys", "scan", "randomkey", "time", "script",
+		"multi", "exec", "discard", "publish", "subscribe", "psubscribe", "unsubscribe",
+		"punsubscribe", "monitor", "wait", "readonly", "readwrite", "asking":
+		return "", false
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro":
+		// EVAL script numkeys key [key ...] arg [arg ...]
+		if len(args) > 3 && args[2].String() != "0" {
+			return args[3].String(), true
+		}
+		return "", false
+	case "memory", "object":
+		// MEMORY USAGE key, OBJECT ENCODING key
+		if len(args) > 2 {
+			return args[2].String(), true
+		}
+		return "", false
+	}
+	return args[1].String(), true
+}
+
+// slotRange is a range of slots served by one master.
+type slotRange struct {
+	start, end int
+	addr       string
+}
+
+// fetchSlots parses CLUSTER SLOTS:
+// [[start, end, [ip, port, id], [replica ip, port, id]...]...]
+func fetchSlots(ctx context.Context, node *Client, queried string) ([]slotRange, error) {
+	resp, err := node.Do(ctx, "CLUSTER", "SLOTS")
+	if nil != err {
+		return nil, err
+	}
+
+	var slots []slotRange
+	for i := range resp.Array {
+		entry := resp.Array[i].Array
+		if len(entry) < 3 || len(entry[2].Array) < 2 {
+			return nil, unexpected(resp)
+		}
+		start, err1 := toInt64(&entry[0])
+		end, err2 := toInt64(&entry[1])
+		if nil != err1 || nil != err2 {
+			return nil, unexpected(resp)
+		}
+		slots = append(slots, slotRange{
+			start: int(start),
+			end:   int(end),
+			addr:  nodeAddr(entry[2].Array[0].Data, entry[2].Array[1].Data, queried),
+		})
+	}
+	return slots, nil
+}
+
+// fetchShards parses CLUSTER SHARDS, maps in RESP3 and flat arrays in RESP2:
+// [{slots: [start, end, ...], nodes: [{ip, port, role, health...}...]}...]
+func fetchShards(ctx context.Context, node *Client, queried string) ([]slotRange, error) {
+	resp, err := node.Do(ctx, "CLUSTER", "SHARDS")
+	if nil != err {
+		return nil, err
+	}
+
+	var slots []slotRange
+	for i := range resp.Array {
+		shard, err := toRespMap(&resp.Array[i])
+		if nil != err {
+			return nil, err
+		}
+
+		var master string
+		for j := range shard["nodes"].Array {
+			info, err := toStringMap(&shard["nodes"].Array[j])
+			if nil != err {
+				return nil, err
+			}
+			if info["role"] == "master" && info["health"] != "fail" {
+				master = nodeAddr(info["ip"], info["port"], queried)
+			}
+		}
+		if "" == master {
+			continue
+		}
+
+		ranges := shard["slots"].Array
+		for j := 0; j+1 < len(ranges); j += 2 {
+			start, err1 := toInt64(&ranges[j])
+			end, err2 := toInt64(&ranges[j+1])
+			if nil != err1 || nil != err2 {
+				return nil, unexpected(resp)
+			}
+			slots = append(slots, slotRange{start: int(start), end: int(end), addr: master})
+		}
+	}
+	return slots, nil
+}
+
+func toRespMap(resp *redisgo.Resp) (map[string]redisgo.Resp, error) {
+	flat, err := toPairs(resp)
+	if nil != err {
+		return nil, err
+	}
+	m := make(map[string]redisgo.Resp, len(flat)/2)
+	for i := 0; i < len(flat); i += 2 {
+		m[flat[i].Data] = flat[i+1]
+	}
+	return m, nil
+}
+
+// nodeAddr joins a node address, an empty ip means the node that was queried.
+func nodeAddr(ip, port, queried string) string {
+	if "" == ip || "?" == ip {
+		ip, _, _ = net.SplitHostPort(queried)
+	}
+	return net.JoinHostPort(ip, port)
+}
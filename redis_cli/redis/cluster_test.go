@@ -0,0 +1,234 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clusterSlots renders a CLUSTER SLOTS reply with one master per range.
+func clusterSlots(ranges ...string) string {
+	reply := "*" + strconv.Itoa(len(ranges)/3) + "\r\n"
+	for i := 0; i+2 < len(ranges); i += 3 {
+		host, port, _ := net.SplitHostPort(ranges[i+2])
+		reply += "*3\r\n:" + ranges[i] + "\r\n:" + ranges[i+1] + "\r\n" +
+			"*3\r\n$" + strconv.Itoa(len(host)) + "\r\n" + host + "\r\n:" + port + "\r\n$2\r\nid\r\n"
+	}
+	return reply
+}
+
+func TestClusterClient(t *testing.T) {
+
+	// the servers are referenced from their own handlers.
+	var mutex sync.Mutex
+	var first, second *testServer
+	asking, refuse := false, false
+	serve := func(self **testServer) func(args []string) string {
+		return func(args []string) string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch strings.ToUpper(args[0]) {
+			case "CLUSTER":
+				if strings.ToUpper(args[1]) == "SHARDS" {
+					return "-ERR unknown subcommand 'SHARDS'\r\n"
+				}
+				// foo (12182) is served by second, bar (5061) by first.
+				return clusterSlots("0", "8191", first.addr, "8192", "16383", second.addr)
+			case "ASKING":
+				if refuse {
+					return "-ERR ASKING refused\r\n"
+				}
+				asking = true
+				return "+OK\r\n"
+			}
+			key := args[1]
+			switch {
+			case key == "moved" && *self == first:
+				return "-MOVED " + strconv.Itoa(Slot(key)) + " " + second.addr + "\r\n"
+			case key == "migrating" && *self == first:
+				return "-ASK " + strconv.Itoa(Slot(key)) + " " + second.addr + "\r\n"
+			case key == "migrating" && !asking:
+				return "-MOVED " + strconv.Itoa(Slot(key)) + " " + first.addr + "\r\n"
+			}
+			asking = false
+			return "$" + strconv.Itoa(len(key)) + "\r\n" + key + "\r\n"
+		}
+	}
+	mutex.Lock()
+	first = newTestServer(t, serve(&first))
+	second = newTestServer(t, serve(&second))
+	mutex.Unlock()
+
+	client, err := DialCluster([]string{first.addr})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if addr := client.SlotAddr(Slot("foo")); addr != second.addr {
+		t.Errorf("SlotAddr(foo) = %q, want %q", addr, second.addr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		key     string
+		server  *testServer
+		command string
+	}{
+		{"foo", second, "GET foo"},
+		{"bar", first, "GET bar"},
+		{"moved", second, "GET moved"},
+		{"migrating", second, "GET migrating"},
+	}
+	for _, tt := range tests {
+		resp, err := client.Do(ctx, "GET", tt.key)
+		if nil != err {
+			t.Fatalf("Do(GET %s) error = %v", tt.key, err)
+		}
+		if resp.Data != tt.key {
+			t.Errorf("Do(GET %s) = %q", tt.key, resp.Data)
+		}
+		if !tt.server.served(tt.command) {
+			t.Errorf("Do(GET %s) not served by %s", tt.key, tt.server.addr)
+		}
+	}
+
+	// ASK redirects a single command, the slot map is kept.
+	if addr := client.SlotAddr(Slot("migrating")); addr != first.addr {
+		t.Errorf("SlotAddr(migrating) = %q, want %q", addr, first.addr)
+	}
+
+	// a refused ASKING fails the command.
+	mutex.Lock()
+	refuse = true
+	mutex.Unlock()
+	if _, err = client.Do(ctx, "GET", "migrating"); nil == err || !strings.Contains(err.Error(), "ASKING refused") {
+		t.Errorf("Do(GET migrating) error = %v, want ASKING refused", err)
+	}
+	mutex.Lock()
+	refuse = false
+	mutex.Unlock()
+
+	// a dropped node connection is replaced and the old client closed.
+	client.mutex.RLock()
+	dropped := client.nodes[first.addr]
+	client.mutex.RUnlock()
+	dropped.Channel().Close(errors.New("connection reset"))
+	if _, err = client.Do(ctx, "GET", "bar"); nil != err {
+		t.Fatalf("Do(GET bar) after a drop error = %v", err)
+	}
+	dropped.mutex.Lock()
+	closed := dropped.closed
+	dropped.mutex.Unlock()
+	if !closed {
+		t.Error("dropped node client not closed")
+	}
+}
+
+func TestClusterClient_Shards(t *testing.T) {
+
+	var mutex sync.Mutex
+	var server *testServer
+	ranges := "*4\r\n:0\r\n:100\r\n:200\r\n:16383\r\n"
+	mutex.Lock()
+	server = newTestServer(t, func(args []string) string {
+		if strings.ToUpper(args[0]) == "CLUSTER" {
+			mutex.Lock()
+			_, port, _ := net.SplitHostPort(server.addr)
+			slots := ranges
+			mutex.Unlock()
+			return "*1\r\n%2\r\n" +
+				"$5\r\nslots\r\n" + slots +
+				"$5\r\nnodes\r\n*1\r\n%3\r\n" +
+				"$2\r\nip\r\n$0\r\n\r\n" +
+				"$4\r\nport\r\n:" + port + "\r\n" +
+				"$4\r\nrole\r\n$6\r\nmaster\r\n"
+		}
+		return "+OK\r\n"
+	})
+	mutex.Unlock()
+
+	client, err := DialCluster([]string{server.addr})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, slot := range []int{0, 100, 200, 16383} {
+		if addr := client.SlotAddr(slot); addr != server.addr {
+			t.Errorf("SlotAddr(%d) = %q, want %q", slot, addr, server.addr)
+		}
+	}
+	if addr := client.SlotAddr(150); addr != "" {
+		t.Errorf("SlotAddr(150) = %q, want unassigned", addr)
+	}
+
+	// slots the node no longer serves lose their owner.
+	mutex.Lock()
+	ranges = "*2\r\n:200\r\n:16383\r\n"
+	mutex.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = client.Refresh(ctx); nil != err {
+		t.Fatal(err)
+	}
+	if addr := client.SlotAddr(0); addr != "" {
+		t.Errorf("SlotAddr(0) after a reshard = %q, want unassigned", addr)
+	}
+}
+
+func TestClusterClient_SlowNode(t *testing.T) {
+
+	// the handshake of the slow node blocks until released.
+	named, release := make(chan struct{}, 1), make(chan struct{})
+	slow := newTestServer(t, func(args []string) string {
+		if strings.ToUpper(args[0]) == "CLIENT" {
+			named <- struct{}{}
+			<-release
+		}
+		return "+OK\r\n"
+	})
+
+	c := &ClusterClient{options: []DialOption{WithClientName("test")}, nodes: make(map[string]*Client)}
+	defer c.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.node(slow.addr)
+		done <- err
+	}()
+
+	select {
+	case <-named:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow node not dialed")
+	}
+
+	// routing is not held up by the dial.
+	routed := make(chan struct{})
+	go func() {
+		c.SlotAddr(0)
+		c.Nodes()
+		close(routed)
+	}()
+	select {
+	case <-routed:
+	case <-time.After(time.Second):
+		t.Error("SlotAddr() blocked while dialing a node")
+	}
+
+	close(release)
+	if err := <-done; nil != err {
+		t.Fatal(err)
+	}
+	if nodes := c.Nodes(); len(nodes) != 1 || nodes[0] != slow.addr {
+		t.Errorf("Nodes() = %v, want [%s]", nodes, slow.addr)
+	}
+}
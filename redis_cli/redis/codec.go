@@ -0,0 +1,171 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+	"github.com/go-netty/go-netty/transport"
+	"github.com/go-netty/go-netty/utils"
+)
+
+// Default limits of a reply read by the codec.
+const (
+	DefaultMaxReplySize  = 512 * 1024 * 1024
+	DefaultMaxReplyDepth = 32
+)
+
+var (
+	// ErrReplyTooLarge is the cause of a ProtocolError for a reply over
+	// the maximum size.
+	ErrReplyTooLarge = redisgo.ErrMaxReplySize
+
+	// ErrReplyTooDeep is the cause of a ProtocolError for a reply nested
+	// deeper than the maximum depth.
+	ErrReplyTooDeep = redisgo.ErrMaxDepth
+)
+
+// ProtocolError closes a channel whose server sent something that is not
+// a valid reply, or one over the codec limits. Every pending request fails
+// with it.
+type ProtocolError struct {
+	Err error
+}
+
+func (e *ProtocolError) Error() string {
+	return "redis: protocol error: " + e.Err.Error()
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// CodecOption configures a codec made by NewCodec.
+type CodecOption func(codec *simpleRedisCodec)
+
+// WithMaxReplySize bounds how many bytes a single reply may take on the
+// wire, a hostile or broken server cannot make the client buffer more.
+func WithMaxReplySize(n int) CodecOption {
+	return func(codec *simpleRedisCodec) {
+		codec.maxReplySize = n
+	}
+}
+
+// WithMaxReplyDepth bounds how deep aggregate replies may be nested.
+func WithMaxReplyDepth(n int) CodecOption {
+	return func(codec *simpleRedisCodec) {
+		codec.maxReplyDepth = n
+	}
+}
+
+// NewCodec returns a codec that decodes replies into *redisgo.Resp and
+// encodes commands given as []redisgo.Value, []string or [][]byte, or a
+// *redisgo.Resp as is. Anything else closes the channel with an error.
+func NewCodec(options ...CodecOption) netty.CodecHandler {
+	codec := &simpleRedisCodec{maxReplySize: DefaultMaxReplySize, maxReplyDepth: DefaultMaxReplyDepth}
+	for _, option := range options {
+		option(codec)
+	}
+	return codec
+}
+
+type simpleRedisCodec struct {
+	decoder       *redisgo.StreamDecoder
+	buffer        []byte
+	maxReplySize  int
+	maxReplyDepth int
+}
+
+func (s *simpleRedisCodec) CodecName() string {
+	return "simple-redis-codec"
+}
+
+func (s *simpleRedisCodec) HandleRead(ctx netty.InboundContext, message netty.Message) {
+
+	// init decoder.
+	if nil == s.decoder {
+		s.decoder = redisgo.NewStreamDecoder(10240,
+			redisgo.WithMaxReplySize(s.maxReplySize), redisgo.WithMaxDepth(s.maxReplyDepth))
+		s.buffer = make([]byte, 10240)
+	}
+
+	var chunk []byte
+	switch r := message.(type) {
+	case transport.Transport:
+		// stream transport, take whatever has arrived.
+		n, err := r.Read(s.buffer)
+		if nil != err {
+			ctx.Close(err)
+			return
+		}
+		chunk = s.buffer[:n]
+	default:
+		// packet transport, the message is a whole datagram or frame.
+		chunk = utils.MustToBytes(message)
+	}
+
+	// decode redis responses.
+	resps, err := s.decoder.Feed(chunk)
+
+	for i := range resps {
+		// post response.
+		ctx.HandleRead(&resps[i])
+	}
+
+	if nil != err {
+		ctx.Close(&ProtocolError{Err: err})
+	}
+}
+
+func (s *simpleRedisCodec) HandleWrite(ctx netty.OutboundContext, message netty.Message) {
+
+	buffer := bytes.NewBuffer(nil)
+
+	var err error
+	switch v := message.(type) {
+	case []redisgo.Value:
+		err = redisgo.EncodeMulti(buffer, v...)
+	case []string:
+		values := make([]redisgo.Value, len(v))
+		for i := range v {
+			values[i] = redisgo.BlukString(v[i])
+		}
+		err = redisgo.EncodeMulti(buffer, values...)
+	case [][]byte:
+		values := make([]redisgo.Value, len(v))
+		for i := range v {
+			values[i] = redisgo.Bluk(v[i])
+		}
+		err = redisgo.EncodeMulti(buffer, values...)
+	case *redisgo.Resp:
+		err = redisgo.EncodeResp(buffer, v)
+	default:
+		err = fmt.Errorf("redis: cannot encode %T", message)
+	}
+
+	// nothing is written, the replies would no longer match the requests.
+	if nil != err {
+		ctx.Close(err)
+		return
+	}
+
+	// post request.
+	ctx.HandleWrite(buffer)
+}
@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+func TestCodec_Limits(t *testing.T) {
+
+	replies := map[string]string{
+		"BAD":   "?what\r\n",
+		"DEEP":  strings.Repeat("*1\r\n", 5) + ":1\r\n",
+		"OPEN":  strings.Repeat("*2\r\n", 5) + ":1\r\n",
+		"LARGE": "$64\r\n" + strings.Repeat("x", 64) + "\r\n",
+		"OK":    "*2\r\n*1\r\n$4\r\nabcd\r\n:1\r\n",
+	}
+	server := newTestServer(t, func(args []string) string {
+		return replies[args[0]]
+	})
+
+	tests := []struct {
+		command string
+		want    error
+	}{
+		{"OK", nil},
+		{"BAD", nil},
+		{"DEEP", ErrReplyTooDeep},
+		{"OPEN", ErrReplyTooDeep},
+		{"LARGE", ErrReplyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			client, err := Dial(server.addr, WithCodecOptions(WithMaxReplySize(32), WithMaxReplyDepth(3)))
+			if nil != err {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, err = client.Do(ctx, tt.command)
+			if "OK" == tt.command {
+				if nil != err {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var protocolErr *ProtocolError
+			if !errors.As(err, &protocolErr) {
+				t.Fatalf("Do error = %v, want a ProtocolError", err)
+			}
+			if nil != tt.want && !errors.Is(err, tt.want) {
+				t.Errorf("Do error = %v, want %v", err, tt.want)
+			}
+			if client.Channel().IsActive() {
+				t.Error("channel still active after a protocol error")
+			}
+		})
+	}
+}
+
+func TestCodec_HandleWrite(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		message interface{}
+		want    string
+	}{
+		{"values", []redisgo.Value{redisgo.BlukString("GET"), redisgo.BlukString("k")}, "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"},
+		{"strings", []string{"GET", "k"}, "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"},
+		{"bytes", [][]byte{[]byte("GET"), []byte("k")}, "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"},
+		{"resp", &redisgo.Resp{Kind: redisgo.SimpleKind, Data: "OK"}, "+OK\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &writeSink{}
+			netty.NewPipeline().AddLast(sink, NewCodec()).FireChannelWrite(tt.message)
+			if len(sink.messages) != 1 {
+				t.Fatalf("written = %d messages, want 1", len(sink.messages))
+			}
+			if got := sink.messages[0].(*bytes.Buffer).String(); got != tt.want {
+				t.Errorf("HandleWrite = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// a message the codec cannot encode fails the requests in flight.
+	server := newTestServer(t, func(args []string) string {
+		return ""
+	})
+	client, err := Dial(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	future := client.Send("BLPOP", "list", 0)
+	for !server.served("BLPOP list 0") {
+		time.Sleep(time.Millisecond)
+	}
+	client.Channel().Write(42)
+	if _, err = future.Wait(); nil == err || !strings.Contains(err.Error(), "cannot encode int") {
+		t.Errorf("BLPOP error = %v, want cannot encode int", err)
+	}
+}
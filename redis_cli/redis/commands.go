@@ -0,0 +1,198 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// SetOption customizes a SET command.
+type SetOption func(args []interface{}) []interface{}
+
+// WithExpiration sets the key ttl, sent as EX when it is whole seconds and PX
+// otherwise. A fraction of a millisecond is rounded up.
+func WithExpiration(ttl time.Duration) SetOption {
+	return func(args []interface{}) []interface{} {
+		if ttl%time.Second == 0 {
+			return append(args, "EX", int64(ttl/time.Second))
+		}
+		return append(args, "PX", milliseconds(ttl))
+	}
+}
+
+// milliseconds returns ttl in milliseconds rounded up, a positive ttl never
+// becomes 0.
+func milliseconds(ttl time.Duration) int64 {
+	ms := int64(ttl / time.Millisecond)
+	if ttl%time.Millisecond > 0 {
+		ms++
+	}
+	return ms
+}
+
+// OnlyIfNotExists only sets the key if it does not exist (NX).
+func OnlyIfNotExists() SetOption {
+	return func(args []interface{}) []interface{} {
+		return append(args, "NX")
+	}
+}
+
+// OnlyIfExists only sets the key if it already exists (XX).
+func OnlyIfExists() SetOption {
+	return func(args []interface{}) []interface{} {
+		return append(args, "XX")
+	}
+}
+
+// KeepTTL retains the ttl of an existing key (KEEPTTL).
+func KeepTTL() SetOption {
+	return func(args []interface{}) []interface{} {
+		return append(args, "KEEPTTL")
+	}
+}
+
+// Z is a sorted set member with its score.
+type Z struct {
+	Member string
+	Score  float64
+}
+
+// Get returns the value of key, or Nil if it does not exist.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	resp, err := c.Do(ctx, "GET", key)
+	if nil != err {
+		return "", err
+	}
+	return toString(resp)
+}
+
+// Set stores value at key, it reports false if a NX/XX condition was not met.
+func (c *Client) Set(ctx context.Context, key string, value interface{}, options ...SetOption) (bool, error) {
+	args := []interface{}{"SET", key, value}
+	for _, option := range options {
+		args = option(args)
+	}
+	resp, err := c.Do(ctx, args...)
+	if nil != err {
+		return false, err
+	}
+	return toBool(resp)
+}
+
+// Del removes keys and returns how many existed.
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	resp, err := c.Do(ctx, append([]interface{}{"DEL"}, strings2args(keys)...)...)
+	if nil != err {
+		return 0, err
+	}
+	return toInt64(resp)
+}
+
+// Incr increments the integer stored at key.
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	resp, err := c.Do(ctx, "INCR", key)
+	if nil != err {
+		return 0, err
+	}
+	return toInt64(resp)
+}
+
+// Expire sets a ttl on key, it reports false if the key does not exist. The
+// ttl is sent with PEXPIRE unless it is whole seconds, it must be positive as
+// redis deletes the key at once otherwise, use Del for that.
+func (c *Client) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, fmt.Errorf("redis: invalid expire time %v", ttl)
+	}
+	args := []interface{}{"EXPIRE", key, int64(ttl / time.Second)}
+	if ttl%time.Second != 0 {
+		args = []interface{}{"PEXPIRE", key, milliseconds(ttl)}
+	}
+	resp, err := c.Do(ctx, args...)
+	if nil != err {
+		return false, err
+	}
+	return toBool(resp)
+}
+
+// HGetAll returns all fields and values of the hash at key.
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	resp, err := c.Do(ctx, "HGETALL", key)
+	if nil != err {
+		return nil, err
+	}
+	return toStringMap(resp)
+}
+
+// LPush prepends values to the list at key and returns its new length.
+func (c *Client) LPush(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	resp, err := c.Do(ctx, append([]interface{}{"LPUSH", key}, values...)...)
+	if nil != err {
+		return 0, err
+	}
+	return toInt64(resp)
+}
+
+// ZRangeWithScores returns the members of the sorted set at key between
+// the start and stop ranks.
+func (c *Client) ZRangeWithScores(ctx context.Context, key string, start, stop int64) ([]Z, error) {
+	resp, err := c.Do(ctx, "ZRANGE", key, start, stop, "WITHSCORES")
+	if nil != err {
+		return nil, err
+	}
+	if resp.Kind != redisgo.ArrayKind {
+		return nil, unexpected(resp)
+	}
+
+	// RESP3 nests [member, score] pairs, RESP2 flattens them.
+	flat := resp.Array
+	if len(flat) > 0 && flat[0].Kind == redisgo.ArrayKind {
+		flat = make([]redisgo.Resp, 0, 2*len(resp.Array))
+		for _, pair := range resp.Array {
+			if len(pair.Array) != 2 {
+				return nil, unexpected(resp)
+			}
+			flat = append(flat, pair.Array...)
+		}
+	}
+	if len(flat)%2 != 0 {
+		return nil, unexpected(resp)
+	}
+
+	zs := make([]Z, len(flat)/2)
+	for i := range zs {
+		if zs[i].Member, err = toString(&flat[2*i]); nil != err {
+			return nil, err
+		}
+		if zs[i].Score, err = toFloat64(&flat[2*i+1]); nil != err {
+			return nil, err
+		}
+	}
+	return zs, nil
+}
+
+func strings2args(ss []string) []interface{} {
+	args := make([]interface{}, len(ss))
+	for i, s := range ss {
+		args[i] = s
+	}
+	return args
+}
@@ -0,0 +1,341 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// ErrClosed is returned for requests sent on, or pending on, a closed connection.
+var ErrClosed = errors.New("redis: connection closed")
+
+// Future is the pending reply of a Request.
+type Future struct {
+	done    chan struct{}
+	resp    *redisgo.Resp
+	replies []redisgo.Resp
+	err     error
+
+	// SUBSCRIBE family commands are answered by several replies.
+	command string
+	expect  int
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// Done is closed once the reply has arrived or the request has failed.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the reply is available, for commands answered by
+// several replies it returns the last one.
+func (f *Future) Wait() (*redisgo.Resp, error) {
+	<-f.done
+	return f.resp, f.err
+}
+
+// Replies returns every reply of a SUBSCRIBE family command, one per
+// channel or pattern, once the Future is done.
+func (f *Future) Replies() []redisgo.Resp {
+	<-f.done
+	return f.replies
+}
+
+func (f *Future) resolve(resp *redisgo.Resp, err error) {
+	f.resp, f.err = resp, err
+	close(f.done)
+}
+
+// Request is a command written to the pipeline, its Future is resolved
+// with the matching reply.
+type Request struct {
+	Args   []redisgo.Value
+	Future *Future
+
+	// part of a transaction, which must not be split across connections.
+	noRetry bool
+}
+
+func NewRequest(args ...redisgo.Value) *Request {
+	return &Request{Args: args, Future: newFuture()}
+}
+
+// PipelineHandler correlates replies with requests in FIFO order, so any
+// number of requests can be in flight on one channel. Once subscribed,
+// published messages are passed on to the next handler as push frames,
+// whatever the protocol, so later handlers never mistake a reply for one.
+type PipelineHandler struct {
+	mutex   sync.Mutex
+	pending []*Request
+	closed  error
+
+	// serializes writes, so the queue has the same order as the bytes on
+	// the wire. It is not held by HandleRead, a write blocked on a full
+	// write queue cannot stop replies from being resolved.
+	writeMutex sync.Mutex
+
+	// Retry, if set, is offered every request left without a reply once
+	// the channel goes inactive, written reports whether it may have
+	// reached the server. Requests it accepts are not failed, so that they
+	// can be written again on a new channel.
+	Retry func(request *Request, written bool) bool
+
+	// subscription state, confirmed by the server.
+	channels      map[string]struct{}
+	patterns      map[string]struct{}
+	pendingPubSub int
+
+	// set once MONITOR succeeded, until RESET.
+	monitoring bool
+
+	// set once HELLO 3 succeeded, pub/sub frames are then always pushed.
+	resp3 bool
+}
+
+func NewPipelineHandler() *PipelineHandler {
+	return &PipelineHandler{
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+}
+
+// Subscriptions returns the channels and patterns currently subscribed.
+func (p *PipelineHandler) Subscriptions() (channels, patterns []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for channel := range p.channels {
+		channels = append(channels, channel)
+	}
+	for pattern := range p.patterns {
+		patterns = append(patterns, pattern)
+	}
+	return
+}
+
+// Pending returns the number of requests waiting for a reply.
+func (p *PipelineHandler) Pending() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.pending)
+}
+
+func (p *PipelineHandler) HandleWrite(ctx netty.OutboundContext, message netty.Message) {
+
+	request, ok := message.(*Request)
+	if !ok {
+		ctx.HandleWrite(message)
+		return
+	}
+
+	// keep the queue in the same order as the bytes on the wire.
+	p.writeMutex.Lock()
+	defer p.writeMutex.Unlock()
+
+	p.mutex.Lock()
+	if closed := p.closed; nil != closed {
+		retry := p.Retry
+		p.mutex.Unlock()
+		if nil == retry || !retry(request, false) {
+			request.Future.resolve(nil, closed)
+		}
+		return
+	}
+
+	future := request.Future
+	if future.command, future.expect = pubsubCommand(request.Args); "" != future.command {
+		p.pendingPubSub++
+	}
+
+	// confirmations of an earlier attempt do not count.
+	future.replies = nil
+	p.pending = append(p.pending, request)
+	p.mutex.Unlock()
+
+	ctx.HandleWrite(request.Args)
+}
+
+func (p *PipelineHandler) HandleRead(ctx netty.InboundContext, message netty.Message) {
+
+	resp := message.(*redisgo.Resp)
+
+	p.mutex.Lock()
+
+	// only treat replies as pub/sub frames while subscribed, over RESP3
+	// a reply shaped like a message is still a reply.
+	var kind string
+	if len(p.channels)+len(p.patterns)+p.pendingPubSub > 0 && (!p.resp3 || resp.Kind == redisgo.PushKind) {
+		kind = pubsubKind(resp)
+	}
+
+	switch kind {
+	case "message", "pmessage":
+		p.mutex.Unlock()
+		ctx.HandleRead(asPush(resp))
+		return
+	case "subscribe", "unsubscribe", "psubscribe", "punsubscribe":
+		p.trackSubscription(kind, resp)
+
+		var future *Future
+		if len(p.pending) > 0 && p.pending[0].Future.command == kind {
+			if future = p.pending[0].Future; p.confirm(future, resp) {
+				p.pending[0] = nil
+				p.pending = p.pending[1:]
+				p.pendingPubSub--
+			} else {
+				future = nil
+			}
+		} else {
+			// the server dropped a subscription on its own.
+			p.mutex.Unlock()
+			ctx.HandleRead(asPush(resp))
+			return
+		}
+		p.mutex.Unlock()
+
+		if nil != future {
+			future.resolve(resp, nil)
+		}
+		return
+	}
+
+	// out-of-band push frames and monitored commands never answer a request.
+	if resp.Kind == redisgo.PushKind || p.monitoring && isMonitorLine(resp) {
+		p.mutex.Unlock()
+		ctx.HandleRead(message)
+		return
+	}
+
+	var future *Future
+	if len(p.pending) > 0 {
+		request := p.pending[0]
+		future = request.Future
+		p.pending[0] = nil
+		p.pending = p.pending[1:]
+
+		// e.g. an error reply to SUBSCRIBE.
+		if "" != future.command {
+			p.pendingPubSub--
+		}
+
+		if resp.Kind != redisgo.ErrorKind && len(request.Args) > 0 {
+			switch strings.ToLower(request.Args[0].String()) {
+			case "monitor":
+				p.monitoring = true
+			case "reset":
+				p.monitoring, p.resp3 = false, false
+			case "hello":
+				if len(request.Args) > 1 {
+					p.resp3 = "3" == request.Args[1].String()
+				}
+			}
+		}
+	}
+	p.mutex.Unlock()
+
+	if nil == future {
+		// unsolicited reply, let the next handler decide.
+		ctx.HandleRead(message)
+		return
+	}
+
+	future.resolve(resp, nil)
+}
+
+func (p *PipelineHandler) HandleInactive(ctx netty.InactiveContext, ex netty.Exception) {
+
+	p.mutex.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.pendingPubSub = 0
+	if p.closed = ex; nil == p.closed {
+		p.closed = ErrClosed
+	}
+	closed, retry := p.closed, p.Retry
+	p.mutex.Unlock()
+
+	for _, request := range pending {
+		if nil == retry || !retry(request, true) {
+			request.Future.resolve(nil, closed)
+		}
+	}
+
+	ctx.HandleInactive(ex)
+}
+
+func (p *PipelineHandler) trackSubscription(kind string, resp *redisgo.Resp) {
+	name := resp.Array[1]
+	switch kind {
+	case "subscribe":
+		p.channels[name.Data] = struct{}{}
+	case "unsubscribe":
+		if !name.Null {
+			delete(p.channels, name.Data)
+		}
+	case "psubscribe":
+		p.patterns[name.Data] = struct{}{}
+	case "punsubscribe":
+		if !name.Null {
+			delete(p.patterns, name.Data)
+		}
+	}
+}
+
+// confirm records one confirmation and reports whether the command is complete.
+func (p *PipelineHandler) confirm(future *Future, resp *redisgo.Resp) bool {
+	future.replies = append(future.replies, *resp)
+	switch future.expect {
+	case unsubscribeAll:
+		return 0 == len(p.channels)
+	case punsubscribeAll:
+		return 0 == len(p.patterns)
+	}
+	future.expect--
+	return future.expect <= 0
+}
+
+// asPush returns a RESP2 pub/sub frame as a push frame.
+func asPush(resp *redisgo.Resp) *redisgo.Resp {
+	if resp.Kind == redisgo.PushKind {
+		return resp
+	}
+	push := *resp
+	push.Kind = redisgo.PushKind
+	return &push
+}
+
+// isMonitorLine reports whether resp is a command reported by MONITOR,
+// such as +1339518083.107412 [0 127.0.0.1:60866] "keys" "*".
+func isMonitorLine(resp *redisgo.Resp) bool {
+	if resp.Kind != redisgo.SimpleKind {
+		return false
+	}
+	timestamp, rest, ok := strings.Cut(resp.Data, " ")
+	if !ok || !strings.HasPrefix(rest, "[") {
+		return false
+	}
+	_, err := strconv.ParseFloat(timestamp, 64)
+	return nil == err
+}
@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// writeSink captures outbound messages instead of writing them to a channel.
+type writeSink struct {
+	messages []netty.Message
+}
+
+func (w *writeSink) HandleWrite(ctx netty.OutboundContext, message netty.Message) {
+	w.messages = append(w.messages, message)
+}
+
+// readSink captures inbound messages that reached the end of the pipeline.
+type readSink struct {
+	messages []netty.Message
+}
+
+func (r *readSink) HandleRead(ctx netty.InboundContext, message netty.Message) {
+	r.messages = append(r.messages, message)
+}
+
+func TestPipelineHandler(t *testing.T) {
+
+	sink, unsolicited, handler := &writeSink{}, &readSink{}, NewPipelineHandler()
+	pl := netty.NewPipeline().AddLast(sink, handler, unsolicited)
+
+	var requests []*Request
+	for i := 0; i < 3; i++ {
+		request := NewRequest(redisgo.BlukString("INCR"), redisgo.BlukString("counter"))
+		pl.FireChannelWrite(request)
+		requests = append(requests, request)
+	}
+
+	if len(sink.messages) != 3 || handler.Pending() != 3 {
+		t.Fatalf("written = %d, pending = %d, want 3", len(sink.messages), handler.Pending())
+	}
+
+	// replies resolve the oldest request first.
+	pl.FireChannelRead(&redisgo.Resp{Kind: redisgo.IntegerKind, Data: "1"})
+	pl.FireChannelRead(&redisgo.Resp{Kind: redisgo.PushKind})
+	pl.FireChannelRead(&redisgo.Resp{Kind: redisgo.IntegerKind, Data: "2"})
+
+	for i, want := range []string{"1", "2"} {
+		resp, err := requests[i].Future.Wait()
+		if err != nil || resp.Data != want {
+			t.Errorf("request %d = %v, %v, want %s", i, resp, err, want)
+		}
+	}
+
+	if len(unsolicited.messages) != 1 {
+		t.Errorf("push frames = %d, want 1", len(unsolicited.messages))
+	}
+
+	// closing the channel fails what is still pending, and what comes later.
+	closed := errors.New("connection reset")
+	pl.FireChannelInactive(closed)
+
+	if _, err := requests[2].Future.Wait(); err != closed {
+		t.Errorf("pending request error = %v, want %v", err, closed)
+	}
+
+	late := NewRequest(redisgo.BlukString("PING"))
+	pl.FireChannelWrite(late)
+	if _, err := late.Future.Wait(); err != closed {
+		t.Errorf("late request error = %v, want %v", err, closed)
+	}
+}
+
+func TestPipelineHandler_Monitor(t *testing.T) {
+
+	sink, monitored, handler := &writeSink{}, &readSink{}, NewPipelineHandler()
+	pl := netty.NewPipeline().AddLast(sink, handler, monitored)
+
+	line := &redisgo.Resp{Kind: redisgo.SimpleKind, Data: `1339518083.107412 [0 127.0.0.1:60866] "keys" "*"`}
+
+	// not a reply to anything before MONITOR.
+	ping := NewRequest(redisgo.BlukString("PING"))
+	pl.FireChannelWrite(ping)
+	pl.FireChannelRead(line)
+	if resp, _ := ping.Future.Wait(); resp != line {
+		t.Errorf("PING = %v, want the line", resp)
+	}
+
+	monitor := NewRequest(redisgo.BlukString("MONITOR"))
+	pl.FireChannelWrite(monitor)
+	pl.FireChannelRead(&redisgo.Resp{Kind: redisgo.SimpleKind, Data: "OK"})
+
+	// monitored commands pass even with RESET in flight.
+	reset := NewRequest(redisgo.BlukString("RESET"))
+	pl.FireChannelWrite(reset)
+	pl.FireChannelRead(line)
+	pl.FireChannelRead(&redisgo.Resp{Kind: redisgo.SimpleKind, Data: "RESET"})
+	if resp, _ := reset.Future.Wait(); resp.Data != "RESET" {
+		t.Errorf("RESET = %v", resp)
+	}
+	if len(monitored.messages) != 1 {
+		t.Errorf("monitored = %d, want 1", len(monitored.messages))
+	}
+
+	ping = NewRequest(redisgo.BlukString("PING"))
+	pl.FireChannelWrite(ping)
+	pl.FireChannelRead(line)
+	if resp, _ := ping.Future.Wait(); resp != line {
+		t.Errorf("PING after RESET = %v, want the line", resp)
+	}
+}
+
+// blockingSink blocks every write after the first until released, like a
+// full write queue.
+type blockingSink struct {
+	writeSink
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSink) HandleWrite(ctx netty.OutboundContext, message netty.Message) {
+	if len(b.messages) > 0 {
+		close(b.blocked)
+		<-b.release
+	}
+	b.writeSink.HandleWrite(ctx, message)
+}
+
+func TestPipelineHandler_BlockedWrite(t *testing.T) {
+
+	sink, handler := &blockingSink{blocked: make(chan struct{}), release: make(chan struct{})}, NewPipelineHandler()
+	pl := netty.NewPipeline().AddLast(sink, handler)
+
+	first, second := NewRequest(redisgo.BlukString("PING")), NewRequest(redisgo.BlukString("PING"))
+	pl.FireChannelWrite(first)
+	go pl.FireChannelWrite(second)
+	<-sink.blocked
+
+	// a blocked write does not hold up replies.
+	go pl.FireChannelRead(&redisgo.Resp{Kind: redisgo.SimpleKind, Data: "PONG"})
+	select {
+	case <-first.Future.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("reply not resolved while a write is blocked")
+	}
+	close(sink.release)
+}
@@ -0,0 +1,440 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// ErrPoolTimeout is returned by Get when no connection was returned to the
+// pool within the borrow timeout.
+var ErrPoolTimeout = errors.New("redis: connection pool timeout")
+
+// PoolOption configures a Pool made by NewPool.
+type PoolOption func(options *poolOptions)
+
+type poolOptions struct {
+	dialOptions   []DialOption
+	minIdle       int
+	maxIdle       int
+	maxActive     int
+	idleTimeout   time.Duration
+	borrowTimeout time.Duration
+	healthCheck   time.Duration
+}
+
+// WithDialOptions sets the options every pooled connection is dialed with.
+// WithReconnect is not allowed, the pool replaces connections that drop.
+func WithDialOptions(options ...DialOption) PoolOption {
+	return func(o *poolOptions) {
+		o.dialOptions = options
+	}
+}
+
+// WithMinIdle keeps at least n idle connections open, they are dialed in
+// the background when borrowed or evicted.
+func WithMinIdle(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.minIdle = n
+	}
+}
+
+// WithMaxIdle closes connections returned while n are idle already.
+func WithMaxIdle(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.maxIdle = n
+	}
+}
+
+// WithMaxActive bounds how many connections are borrowed at once, Get waits
+// for a connection to be returned beyond that. n must be positive, NewPool
+// fails otherwise.
+func WithMaxActive(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.maxActive = n
+	}
+}
+
+// WithIdleTimeout closes connections that stayed idle for longer than d.
+func WithIdleTimeout(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.idleTimeout = d
+	}
+}
+
+// WithBorrowTimeout bounds how long Get waits for a connection, zero waits
+// for as long as the context of Get allows.
+func WithBorrowTimeout(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.borrowTimeout = d
+	}
+}
+
+// WithHealthCheck sets how often idle connections are checked with PING,
+// expired ones evicted and the pool topped up to its minimum. Zero turns
+// the checks off.
+func WithHealthCheck(interval time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.healthCheck = interval
+	}
+}
+
+// PoolStats is a snapshot of the pool counters.
+type PoolStats struct {
+	Hits     uint64 // borrows served by an idle connection
+	Misses   uint64 // borrows that dialed a new connection
+	Timeouts uint64 // borrows that gave up waiting
+	Active   int    // connections borrowed
+	Idle     int    // connections waiting in the pool
+}
+
+type idleConn struct {
+	client *Client
+	since  time.Time
+}
+
+// Pool keeps connections to one redis server for commands that need one
+// of their own, such as blocking commands or WATCH, and to spread load
+// over several sockets. Connections that drop are removed from the pool.
+type Pool struct {
+	addr    string
+	options poolOptions
+	tokens  chan struct{}
+	done    chan struct{}
+
+	mutex  sync.Mutex
+	idle   []idleConn
+	active int
+	closed bool
+
+	hits, misses, timeouts uint64
+}
+
+// NewPool creates a pool of connections to addr, it fails if the minimum
+// number of idle connections cannot be dialed.
+func NewPool(addr string, options ...PoolOption) (*Pool, error) {
+	o := poolOptions{
+		maxIdle:       10,
+		maxActive:     10 * runtime.GOMAXPROCS(0),
+		idleTimeout:   5 * time.Minute,
+		borrowTimeout: 5 * time.Second,
+		healthCheck:   30 * time.Second,
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	if o.maxActive <= 0 {
+		return nil, fmt.Errorf("redis: invalid max active connections %d", o.maxActive)
+	}
+	var dial dialOptions
+	for _, option := range o.dialOptions {
+		option(&dial)
+	}
+	if nil != dial.backoff {
+		return nil, errors.New("redis: pooled connections cannot reconnect")
+	}
+	if o.maxIdle < o.minIdle {
+		o.maxIdle = o.minIdle
+	}
+
+	p := &Pool{addr: addr, options: o, tokens: make(chan struct{}, o.maxActive), done: make(chan struct{})}
+	if err := p.fill(); nil != err {
+		p.Close()
+		return nil, err
+	}
+	if o.healthCheck > 0 {
+		go p.maintain()
+	}
+	return p, nil
+}
+
+// Get borrows a connection, it must be returned with Put. Get waits while
+// the maximum number of connections is borrowed.
+func (p *Pool) Get(ctx context.Context) (*Client, error) {
+
+	var timeout <-chan time.Time
+	if p.options.borrowTimeout > 0 {
+		timer := time.NewTimer(p.options.borrowTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.tokens <- struct{}{}:
+	case <-timeout:
+		atomic.AddUint64(&p.timeouts, 1)
+		return nil, ErrPoolTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		p.mutex.Lock()
+		if p.closed {
+			p.mutex.Unlock()
+			<-p.tokens
+			return nil, ErrClosed
+		}
+		if 0 == len(p.idle) {
+			p.active++
+			p.mutex.Unlock()
+			break
+		}
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mutex.Unlock()
+
+		if conn.client.Channel().IsActive() {
+			p.mutex.Lock()
+			p.active++
+			p.mutex.Unlock()
+			atomic.AddUint64(&p.hits, 1)
+			return conn.client, nil
+		}
+		conn.client.Close()
+	}
+
+	atomic.AddUint64(&p.misses, 1)
+	client, err := p.dial()
+	if nil != err {
+		p.mutex.Lock()
+		p.active--
+		p.mutex.Unlock()
+		<-p.tokens
+		return nil, err
+	}
+	return client, nil
+}
+
+// Put returns a borrowed connection. It must be left as it was borrowed,
+// without subscriptions, WATCH or a pending MULTI.
+func (p *Pool) Put(client *Client) {
+	p.mutex.Lock()
+	p.active--
+	keep := !p.closed && len(p.idle) < p.options.maxIdle && client.Channel().IsActive()
+	if keep {
+		p.idle = append(p.idle, idleConn{client: client, since: time.Now()})
+	}
+	p.mutex.Unlock()
+	<-p.tokens
+
+	if !keep {
+		client.Close()
+	}
+}
+
+// Do runs a command on a borrowed connection.
+func (p *Pool) Do(ctx context.Context, args ...interface{}) (*redisgo.Resp, error) {
+	client, err := p.Get(ctx)
+	if nil != err {
+		return nil, err
+	}
+	defer p.Put(client)
+	return client.Do(ctx, args...)
+}
+
+// Watch runs fn as an optimistic transaction on keys over a borrowed
+// connection, as Client.Watch does.
+func (p *Pool) Watch(ctx context.Context, retries int, fn func(tx *Tx) error, keys ...string) error {
+	client, err := p.Get(ctx)
+	if nil != err {
+		return err
+	}
+	defer p.Put(client)
+	return watch(ctx, client, retries, fn, keys)
+}
+
+// Stats returns the pool counters.
+func (p *Pool) Stats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return PoolStats{
+		Hits:     atomic.LoadUint64(&p.hits),
+		Misses:   atomic.LoadUint64(&p.misses),
+		Timeouts: atomic.LoadUint64(&p.timeouts),
+		Active:   p.active,
+		Idle:     len(p.idle),
+	}
+}
+
+// Close closes the idle connections, borrowed ones are closed once they
+// are returned.
+func (p *Pool) Close() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mutex.Unlock()
+
+	close(p.done)
+	for _, conn := range idle {
+		conn.client.Close()
+	}
+	return nil
+}
+
+func (p *Pool) dial() (*Client, error) {
+	options := append([]DialOption{onInactive(p.remove)}, p.options.dialOptions...)
+	return Dial(p.addr, options...)
+}
+
+// remove drops a connection whose channel went inactive while idle, a
+// borrowed one is left to Put.
+func (p *Pool) remove(client *Client) {
+	if _, ok := p.take(client); ok {
+		client.Close()
+	}
+}
+
+// take removes client from the idle connections, ok is false if it was
+// not idle.
+func (p *Pool) take(client *Client) (conn idleConn, ok bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i := range p.idle {
+		if conn = p.idle[i]; conn.client == client {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			return conn, true
+		}
+	}
+	return idleConn{}, false
+}
+
+// fill dials idle connections up to the minimum.
+func (p *Pool) fill() error {
+	for {
+		p.mutex.Lock()
+		enough := p.closed || len(p.idle) >= p.options.minIdle
+		p.mutex.Unlock()
+		if enough {
+			return nil
+		}
+
+		client, err := p.dial()
+		if nil != err {
+			return err
+		}
+
+		p.mutex.Lock()
+		if p.closed {
+			p.mutex.Unlock()
+			client.Close()
+			return nil
+		}
+		p.idle = append(p.idle, idleConn{client: client, since: time.Now()})
+		p.mutex.Unlock()
+	}
+}
+
+func (p *Pool) maintain() {
+	ticker := time.NewTicker(p.options.healthCheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.check()
+		}
+	}
+}
+
+// check evicts expired idle connections, PINGs the others and dials
+// replacements for those it dropped. Connections are taken out of the pool
+// one at a time while they are checked, so that Get never hands out one
+// in use and the others stay available.
+func (p *Pool) check() {
+
+	var expired []*Client
+	p.mutex.Lock()
+	var checked []*Client
+	idle := p.idle[:0]
+	for _, conn := range p.idle {
+		if time.Since(conn.since) > p.options.idleTimeout {
+			expired = append(expired, conn.client)
+		} else {
+			idle = append(idle, conn)
+			checked = append(checked, conn.client)
+		}
+	}
+	p.idle = idle
+	p.mutex.Unlock()
+
+	for _, client := range expired {
+		client.Close()
+	}
+
+	for _, client := range checked {
+		// borrowed or dropped since.
+		conn, ok := p.take(client)
+		if !ok {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.options.healthCheck)
+		_, err := conn.client.Do(ctx, "PING")
+		cancel()
+
+		// it was idle before the check, it goes back even if Put filled
+		// the pool meanwhile, among the oldest connections.
+		p.mutex.Lock()
+		keep := nil == err && !p.closed
+		if keep {
+			p.idle = append([]idleConn{conn}, p.idle...)
+		}
+		p.mutex.Unlock()
+
+		if !keep {
+			conn.client.Close()
+		}
+	}
+
+	// dial errors are retried on the next check.
+	p.fill()
+}
+
+// onInactive calls fn once the channel of the client goes inactive.
+func onInactive(fn func(client *Client)) DialOption {
+	return func(options *dialOptions) {
+		options.inactive = fn
+	}
+}
+
+// inactiveHandler reports a dropped channel to the pool owning the client.
+type inactiveHandler struct {
+	client *Client
+	fn     func(client *Client)
+}
+
+func (h *inactiveHandler) HandleInactive(ctx netty.InactiveContext, ex netty.Exception) {
+	go h.fn(h.client)
+	ctx.HandleInactive(ex)
+}
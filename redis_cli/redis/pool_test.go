@@ -0,0 +1,203 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+
+	server := newTestServer(t, func(args []string) string {
+		return "+PONG\r\n"
+	})
+
+	pool, err := NewPool(server.addr, WithMinIdle(1), WithMaxActive(2), WithBorrowTimeout(50*time.Millisecond))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a, err := pool.Get(ctx)
+	if nil != err {
+		t.Fatal(err)
+	}
+	b, err := pool.Get(ctx)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("the same connection was borrowed twice")
+	}
+	if _, err = pool.Get(ctx); err != ErrPoolTimeout {
+		t.Fatalf("Get error = %v, want %v", err, ErrPoolTimeout)
+	}
+
+	want := PoolStats{Hits: 1, Misses: 1, Timeouts: 1, Active: 2}
+	if stats := pool.Stats(); stats != want {
+		t.Errorf("Stats = %+v, want %+v", stats, want)
+	}
+
+	pool.Put(a)
+	pool.Put(b)
+	if _, err = pool.Do(ctx, "PING"); nil != err {
+		t.Fatal(err)
+	}
+
+	want = PoolStats{Hits: 2, Misses: 1, Timeouts: 1, Idle: 2}
+	if stats := pool.Stats(); stats != want {
+		t.Errorf("Stats = %+v, want %+v", stats, want)
+	}
+
+	// dropped idle connections leave the pool.
+	a.Channel().Close(errors.New("dropped"))
+	for pool.Stats().Idle != 1 {
+		if nil != ctx.Err() {
+			t.Fatal("dropped connection still idle")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	pool.Close()
+	if _, err = pool.Get(ctx); err != ErrClosed {
+		t.Errorf("Get error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestPool_HealthCheck(t *testing.T) {
+
+	server := newTestServer(t, func(args []string) string {
+		return "+PONG\r\n"
+	})
+
+	pool, err := NewPool(server.addr, WithMinIdle(2), WithIdleTimeout(time.Hour), WithHealthCheck(10*time.Millisecond))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	for !server.served("PING") {
+		time.Sleep(time.Millisecond)
+	}
+
+	// expired connections are replaced up to the minimum.
+	pool.mutex.Lock()
+	old := pool.idle[0].client
+	pool.idle[0].since = time.Now().Add(-2 * time.Hour)
+	pool.mutex.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for old.Channel().IsActive() {
+		if time.Now().After(deadline) {
+			t.Fatal("expired connection not closed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for pool.Stats().Idle != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats = %+v, want 2 idle", pool.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPool_CheckedNotBorrowed(t *testing.T) {
+
+	// PINGs of the health check block until released.
+	pinged, release := make(chan struct{}, 1), make(chan struct{})
+	server := newTestServer(t, func(args []string) string {
+		if "PING" == args[0] {
+			select {
+			case pinged <- struct{}{}:
+				<-release
+			default:
+			}
+		}
+		return "+PONG\r\n"
+	})
+
+	pool, err := NewPool(server.addr, WithMinIdle(2), WithHealthCheck(10*time.Millisecond), WithBorrowTimeout(0))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	select {
+	case <-pinged:
+	case <-ctx.Done():
+		t.Fatal("idle connection not checked")
+	}
+
+	// only the connection being checked is out of the pool.
+	if idle := pool.Stats().Idle; idle != 1 {
+		t.Errorf("Stats().Idle = %d while checking, want 1", idle)
+	}
+	client, err := pool.Get(ctx)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("Stats = %+v, want a hit", stats)
+	}
+	pool.Put(client)
+	close(release)
+
+	for pool.Stats().Idle != 2 {
+		if nil != ctx.Err() {
+			t.Fatalf("Stats = %+v, want 2 idle", pool.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPool_DroppedWhileBorrowed(t *testing.T) {
+
+	server := newTestServer(t, func(args []string) string {
+		return "+PONG\r\n"
+	})
+
+	pool, err := NewPool(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := pool.Get(ctx)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// the borrower still owns the client, it is closed once returned.
+	pool.remove(client)
+	client.mutex.Lock()
+	closed := client.closed
+	client.mutex.Unlock()
+	if closed {
+		t.Error("borrowed client closed by the pool")
+	}
+	pool.Put(client)
+	if idle := pool.Stats().Idle; idle != 1 {
+		t.Errorf("Stats().Idle = %d, want 1", idle)
+	}
+}
+
+func TestNewPool_Options(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if _, err := NewPool("127.0.0.1:0", WithMaxActive(n)); nil == err {
+			t.Errorf("NewPool() with WithMaxActive(%d) expect error", n)
+		}
+	}
+	if _, err := NewPool("127.0.0.1:0", WithDialOptions(WithReconnect(DefaultBackoff))); nil == err {
+		t.Errorf("NewPool() with WithReconnect expect error")
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// expected replies of UNSUBSCRIBE and PUNSUBSCRIBE without arguments,
+// one per subscription the server still holds.
+const (
+	unsubscribeAll  = -1
+	punsubscribeAll = -2
+)
+
+// pubsubCommand returns the lower-cased SUBSCRIBE family command and the
+// number of replies it expects, or "" for any other command.
+func pubsubCommand(args []redisgo.Value) (string, int) {
+	if len(args) == 0 {
+		return "", 0
+	}
+	switch command := strings.ToLower(args[0].String()); command {
+	case "subscribe", "psubscribe":
+		return command, len(args) - 1
+	case "unsubscribe":
+		if len(args) == 1 {
+			return command, unsubscribeAll
+		}
+		return command, len(args) - 1
+	case "punsubscribe":
+		if len(args) == 1 {
+			return command, punsubscribeAll
+		}
+		return command, len(args) - 1
+	}
+	return "", 0
+}
+
+// pubsubKind returns the lower-cased kind of a pub/sub frame, such as
+// "message" or "subscribe", or "" if resp is not one. Over RESP2 the frames
+// are arrays, only the pipeline can tell them from replies.
+func pubsubKind(resp *redisgo.Resp) string {
+	if resp.Kind != redisgo.ArrayKind && resp.Kind != redisgo.PushKind {
+		return ""
+	}
+	if len(resp.Array) < 3 {
+		return ""
+	}
+	switch kind := strings.ToLower(resp.Array[0].Data); kind {
+	case "subscribe", "unsubscribe", "psubscribe", "punsubscribe", "message":
+		return kind
+	case "pmessage":
+		if len(resp.Array) == 4 {
+			return kind
+		}
+	}
+	return ""
+}
+
+// Message is a message published to a subscribed channel.
+type Message struct {
+	Pattern string // matching pattern, for PSUBSCRIBE
+	Channel string
+	Payload string
+}
+
+// parseMessage parses a message passed on by the PipelineHandler, always
+// a push frame.
+func parseMessage(resp *redisgo.Resp) (*Message, bool) {
+	if resp.Kind != redisgo.PushKind {
+		return nil, false
+	}
+	switch pubsubKind(resp) {
+	case "message":
+		return &Message{Channel: resp.Array[1].Data, Payload: resp.Array[2].Data}, true
+	case "pmessage":
+		return &Message{Pattern: resp.Array[1].Data, Channel: resp.Array[2].Data, Payload: resp.Array[3].Data}, true
+	}
+	return nil, false
+}
+
+// PubSubHandler dispatches published messages to per-channel and
+// per-pattern callbacks. Callbacks run on the channel read loop, so they
+// must not block.
+type PubSubHandler struct {
+	mutex    sync.RWMutex
+	channels map[string]func(*Message)
+	patterns map[string]func(*Message)
+	fallback func(*Message)
+}
+
+// NewPubSubHandler creates a handler, fallback receives messages that no
+// callback claims and may be nil.
+func NewPubSubHandler(fallback func(*Message)) *PubSubHandler {
+	return &PubSubHandler{
+		channels: make(map[string]func(*Message)),
+		patterns: make(map[string]func(*Message)),
+		fallback: fallback,
+	}
+}
+
+// Handle registers fn for messages on channel.
+func (p *PubSubHandler) Handle(channel string, fn func(*Message)) {
+	p.mutex.Lock()
+	p.channels[channel] = fn
+	p.mutex.Unlock()
+}
+
+// HandlePattern registers fn for messages matching pattern.
+func (p *PubSubHandler) HandlePattern(pattern string, fn func(*Message)) {
+	p.mutex.Lock()
+	p.patterns[pattern] = fn
+	p.mutex.Unlock()
+}
+
+// Remove drops the callbacks of channels.
+func (p *PubSubHandler) Remove(channels ...string) {
+	p.mutex.Lock()
+	for _, channel := range channels {
+		delete(p.channels, channel)
+	}
+	p.mutex.Unlock()
+}
+
+// RemovePattern drops the callbacks of patterns.
+func (p *PubSubHandler) RemovePattern(patterns ...string) {
+	p.mutex.Lock()
+	for _, pattern := range patterns {
+		delete(p.patterns, pattern)
+	}
+	p.mutex.Unlock()
+}
+
+func (p *PubSubHandler) HandleRead(ctx netty.InboundContext, message netty.Message) {
+
+	msg, ok := parseMessage(message.(*redisgo.Resp))
+	if !ok {
+		ctx.HandleRead(message)
+		return
+	}
+
+	p.mutex.RLock()
+	fn := p.channels[msg.Channel]
+	if "" != msg.Pattern {
+		fn = p.patterns[msg.Pattern]
+	}
+	if nil == fn {
+		fn = p.fallback
+	}
+	p.mutex.RUnlock()
+
+	if nil == fn {
+		ctx.HandleRead(message)
+		return
+	}
+
+	fn(msg)
+}
+
+// Subscribe subscribes to channels and delivers their messages to fn.
+// Over RESP2 the connection only accepts SUBSCRIBE family commands and
+// PING until every subscription is dropped.
+func (c *Client) Subscribe(ctx context.Context, fn func(*Message), channels ...string) error {
+	for _, channel := range channels {
+		c.pubsub.Handle(channel, fn)
+	}
+	_, err := c.Do(ctx, append([]interface{}{"SUBSCRIBE"}, strings2args(channels)...)...)
+	return err
+}
+
+// PSubscribe subscribes to patterns and delivers their messages to fn.
+func (c *Client) PSubscribe(ctx context.Context, fn func(*Message), patterns ...string) error {
+	for _, pattern := range patterns {
+		c.pubsub.HandlePattern(pattern, fn)
+	}
+	_, err := c.Do(ctx, append([]interface{}{"PSUBSCRIBE"}, strings2args(patterns)...)...)
+	return err
+}
+
+// Unsubscribe drops channels, or every channel if none is given.
+func (c *Client) Unsubscribe(ctx context.Context, channels ...string) error {
+	future := c.Send(append([]interface{}{"UNSUBSCRIBE"}, strings2args(channels)...)...)
+	if _, err := wait(ctx, future); nil != err {
+		return err
+	}
+	for _, reply := range future.Replies() {
+		c.pubsub.Remove(reply.Array[1].Data)
+	}
+	return nil
+}
+
+// PUnsubscribe drops patterns, or every pattern if none is given.
+func (c *Client) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	future := c.Send(append([]interface{}{"PUNSUBSCRIBE"}, strings2args(patterns)...)...)
+	if _, err := wait(ctx, future); nil != err {
+		return err
+	}
+	for _, reply := range future.Replies() {
+		c.pubsub.RemovePattern(reply.Array[1].Data)
+	}
+	return nil
+}
+
+// Publish posts message to channel and returns the number of receivers.
+func (c *Client) Publish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	resp, err := c.Do(ctx, "PUBLISH", channel, message)
+	if nil != err {
+		return 0, err
+	}
+	return toInt64(resp)
+}
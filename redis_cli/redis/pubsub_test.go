@@ -0,0 +1,185 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+func decodeResp(t *testing.T, input string) *redisgo.Resp {
+	resps, err := redisgo.NewStreamDecoder(1024).Feed([]byte(input))
+	if nil != err || len(resps) != 1 {
+		t.Fatalf("decode %q = %v, %v", input, resps, err)
+	}
+	return &resps[0]
+}
+
+func pubsubFrame(t *testing.T, items ...string) *redisgo.Resp {
+	raw := "*" + strconv.Itoa(len(items)) + "\r\n"
+	for _, item := range items {
+		if strings.HasPrefix(item, ":") {
+			raw += item + "\r\n"
+		} else {
+			raw += "$" + strconv.Itoa(len(item)) + "\r\n" + item + "\r\n"
+		}
+	}
+	return decodeResp(t, raw)
+}
+
+// pushFrame is a pub/sub frame as the PipelineHandler passes it on.
+func pushFrame(t *testing.T, items ...string) *redisgo.Resp {
+	return asPush(pubsubFrame(t, items...))
+}
+
+func TestPipelineHandler_PubSub(t *testing.T) {
+
+	sink, pushed, handler := &writeSink{}, &readSink{}, NewPipelineHandler()
+	pl := netty.NewPipeline().AddLast(sink, handler, pushed)
+
+	subscribe := NewRequest(Args("SUBSCRIBE", "a", "b")...)
+	pl.FireChannelWrite(subscribe)
+
+	pl.FireChannelRead(pubsubFrame(t, "subscribe", "a", ":1"))
+	select {
+	case <-subscribe.Future.Done():
+		t.Fatal("SUBSCRIBE resolved before every channel was confirmed")
+	default:
+	}
+	pl.FireChannelRead(pubsubFrame(t, "subscribe", "b", ":2"))
+	if replies := subscribe.Future.Replies(); len(replies) != 2 {
+		t.Fatalf("SUBSCRIBE replies = %d, want 2", len(replies))
+	}
+
+	// messages are passed on, replies still answer requests in order.
+	ping := NewRequest(Args("PING")...)
+	pl.FireChannelWrite(ping)
+	pl.FireChannelRead(pubsubFrame(t, "message", "a", "hello"))
+	pl.FireChannelRead(pubsubFrame(t, "pong", ""))
+	if resp, _ := ping.Future.Wait(); resp.Array[0].Data != "pong" {
+		t.Errorf("PING = %v, want pong", resp)
+	}
+	if len(pushed.messages) != 1 || pushed.messages[0].(*redisgo.Resp).Kind != redisgo.PushKind {
+		t.Errorf("messages = %v, want 1 push frame", pushed.messages)
+	}
+
+	channels, _ := handler.Subscriptions()
+	sort.Strings(channels)
+	if !reflect.DeepEqual(channels, []string{"a", "b"}) {
+		t.Errorf("Subscriptions() = %v", channels)
+	}
+
+	// UNSUBSCRIBE without arguments completes once nothing is left.
+	unsubscribe := NewRequest(Args("UNSUBSCRIBE")...)
+	pl.FireChannelWrite(unsubscribe)
+	pl.FireChannelRead(pubsubFrame(t, "unsubscribe", "a", ":1"))
+	pl.FireChannelRead(pubsubFrame(t, "unsubscribe", "b", ":0"))
+	if replies := unsubscribe.Future.Replies(); len(replies) != 2 {
+		t.Fatalf("UNSUBSCRIBE replies = %d, want 2", len(replies))
+	}
+
+	// back to normal mode, arrays that look like messages are replies.
+	lrange := NewRequest(Args("LRANGE", "l", 0, -1)...)
+	pl.FireChannelWrite(lrange)
+	pl.FireChannelRead(pubsubFrame(t, "message", "a", "b"))
+	if resp, _ := lrange.Future.Wait(); len(resp.Array) != 3 {
+		t.Errorf("LRANGE = %v", resp)
+	}
+
+	// over RESP3 only push frames are pub/sub, even while subscribed.
+	hello := NewRequest(Args("HELLO", 3)...)
+	pl.FireChannelWrite(hello)
+	pl.FireChannelRead(decodeResp(t, "%1\r\n$5\r\nproto\r\n:3\r\n"))
+	subscribe = NewRequest(Args("SUBSCRIBE", "a")...)
+	pl.FireChannelWrite(subscribe)
+	pl.FireChannelRead(pushFrame(t, "subscribe", "a", ":1"))
+	subscribe.Future.Wait()
+
+	lrange = NewRequest(Args("LRANGE", "l", 0, -1)...)
+	pl.FireChannelWrite(lrange)
+	pl.FireChannelRead(pubsubFrame(t, "message", "a", "b"))
+	if resp, _ := lrange.Future.Wait(); resp.Kind != redisgo.ArrayKind || len(resp.Array) != 3 {
+		t.Errorf("LRANGE over RESP3 = %v", resp)
+	}
+}
+
+func TestPubSubHandler(t *testing.T) {
+
+	var got []string
+	record := func(prefix string) func(*Message) {
+		return func(msg *Message) {
+			got = append(got, prefix+":"+msg.Pattern+":"+msg.Channel+":"+msg.Payload)
+		}
+	}
+
+	unclaimed, handler := &readSink{}, NewPubSubHandler(nil)
+	handler.Handle("news", record("channel"))
+	handler.HandlePattern("n*", record("pattern"))
+	pl := netty.NewPipeline().AddLast(handler, unclaimed)
+
+	pl.FireChannelRead(pushFrame(t, "message", "news", "a"))
+	pl.FireChannelRead(pushFrame(t, "pmessage", "n*", "news", "b"))
+	pl.FireChannelRead(pushFrame(t, "message", "other", "c"))
+	handler.Remove("news")
+	pl.FireChannelRead(pushFrame(t, "message", "news", "d"))
+
+	// an unsolicited reply is no message, even if it looks like one.
+	pl.FireChannelRead(pubsubFrame(t, "message", "news", "e"))
+
+	if want := []string{"channel::news:a", "pattern:n*:news:b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dispatched = %v, want %v", got, want)
+	}
+	if len(unclaimed.messages) != 3 {
+		t.Errorf("unclaimed = %d, want 3", len(unclaimed.messages))
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+
+	server := newTestServer(t, func(args []string) string {
+		switch args[0] {
+		case "SUBSCRIBE":
+			return "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n" +
+				"*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n"
+		case "UNSUBSCRIBE":
+			return "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:0\r\n"
+		}
+		return "+OK\r\n"
+	})
+
+	client, err := Dial(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	messages := make(chan *Message, 1)
+	if err = client.Subscribe(ctx, func(msg *Message) { messages <- msg }, "news"); nil != err {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg.Channel != "news" || msg.Payload != "hello" {
+			t.Errorf("message = %+v", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("no message received")
+	}
+
+	if err = client.Unsubscribe(ctx); nil != err {
+		t.Fatal(err)
+	}
+	if _, err = client.Do(ctx, "PING"); nil != err {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// Backoff is the delay between reconnect attempts, it starts at Min and
+// grows by Factor up to Max, each delay is randomized by +/- Jitter of
+// itself so that many clients do not reconnect in lockstep. Jitter is
+// capped at 1, a delay is never negative.
+type Backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+	Jitter float64
+
+	// MaxAttempts gives up after that many failed attempts, zero retries
+	// until the client is closed.
+	MaxAttempts int
+}
+
+// DefaultBackoff is used for the zero fields of a Backoff.
+var DefaultBackoff = Backoff{Min: 100 * time.Millisecond, Max: 10 * time.Second, Factor: 2, Jitter: 0.2}
+
+// Delay returns the delay before attempt, counting from zero.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if 0 == b.Min {
+		b.Min = DefaultBackoff.Min
+	}
+	if 0 == b.Max {
+		b.Max = DefaultBackoff.Max
+	}
+	if b.Factor < 1 {
+		b.Factor = DefaultBackoff.Factor
+	}
+
+	delay := math.Min(float64(b.Min)*math.Pow(b.Factor, float64(attempt)), float64(b.Max))
+	if b.Jitter > 0 {
+		delay += delay * math.Min(b.Jitter, 1) * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// RetryPolicy decides whether a command that was written but not answered
+// when the connection dropped is sent again after reconnecting, commands
+// it rejects fail with the connection error. Commands that never reached
+// the connection are always sent again.
+type RetryPolicy func(args []redisgo.Value) bool
+
+// NeverRetry fails every command in flight, the default.
+func NeverRetry(args []redisgo.Value) bool {
+	return false
+}
+
+// AlwaysRetry sends every command in flight again, a write may then be
+// applied twice.
+func AlwaysRetry(args []redisgo.Value) bool {
+	return true
+}
+
+// RetryIdempotent sends read-only commands and subscriptions again, which
+// cannot change the dataset when applied twice.
+func RetryIdempotent(args []redisgo.Value) bool {
+	if 0 == len(args) {
+		return false
+	}
+	switch strings.ToLower(args[0].String()) {
+	case "get", "mget", "getrange", "strlen", "exists", "type", "ttl", "pttl", "expiretime",
+		"pexpiretime", "dump", "keys", "scan", "randomkey", "dbsize", "hget", "hmget", "hgetall",
+		"hkeys", "hvals", "hlen", "hexists", "hstrlen", "hscan", "hrandfield", "lrange", "lindex",
+		"llen", "lpos", "smembers", "sismember", "smismember", "scard", "srandmember", "sscan",
+		"sinter", "sintercard", "sunion", "sdiff", "zrange", "zrangebyscore", "zrangebylex",
+		"zrevrange", "zrevrangebyscore", "zrevrangebylex", "zrank", "zrevrank", "zscore",
+		"zmscore", "zcard", "zcount", "zlexcount", "zscan", "zrandmember", "xrange", "xrevrange",
+		"xlen", "xread", "pfcount", "getbit", "bitcount", "bitpos", "geopos", "geodist", "geohash",
+		"geosearch", "object", "memory", "ping", "echo", "time", "info", "lastsave",
+		"subscribe", "psubscribe", "unsubscribe", "punsubscribe":
+		return true
+	}
+	return false
+}
+
+// WithReconnect redials with backoff once the connection drops, the
+// handshake is sent again and subscriptions are restored. Commands sent
+// meanwhile are held back until the connection is up again.
+func WithReconnect(backoff Backoff) DialOption {
+	return func(options *dialOptions) {
+		options.backoff = &backoff
+	}
+}
+
+// WithRetryPolicy sets which commands in flight are sent again after
+// reconnecting, it only applies together with WithReconnect.
+func WithRetryPolicy(policy RetryPolicy) DialOption {
+	return func(options *dialOptions) {
+		options.retry = policy
+	}
+}
+
+// reconnectHandler is the last handler of a reconnecting client pipeline,
+// it starts redialing once the channel goes inactive.
+type reconnectHandler struct {
+	client   *Client
+	pipeline *PipelineHandler
+}
+
+func (r *reconnectHandler) HandleInactive(ctx netty.InactiveContext, ex netty.Exception) {
+	if r.client.startReconnect() {
+		go r.client.reconnect(r.pipeline, ex)
+	}
+	ctx.HandleInactive(ex)
+}
+
+// retry is the Retry hook of a reconnecting pipeline, it holds request
+// back for the next connection.
+func (c *Client) retry(request *Request, written bool) bool {
+	if request.noRetry || written && !c.options.retry(request.Args) {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed || nil != c.failed {
+		return false
+	}
+	c.queued = append(c.queued, request)
+	return true
+}
+
+// startReconnect reports whether the client should redial, only one
+// reconnect runs at a time and none once the client is closed.
+func (c *Client) startReconnect() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed || c.reconnecting {
+		return false
+	}
+	c.reconnecting = true
+	return true
+}
+
+// reconnect redials until a connection is set up, or until the attempts
+// are exhausted or the client is closed.
+func (c *Client) reconnect(previous *PipelineHandler, cause error) {
+
+	channels, patterns := previous.Subscriptions()
+
+	for attempt := 0; ; attempt++ {
+		if max := c.options.backoff.MaxAttempts; max > 0 && attempt >= max {
+			c.giveUp(fmt.Errorf("redis: reconnect to %s failed after %d attempts: %w", c.addr, attempt, cause))
+			return
+		}
+
+		select {
+		case <-time.After(c.options.backoff.Delay(attempt)):
+		case <-c.done:
+			c.giveUp(ErrClosed)
+			return
+		}
+
+		ch, err := c.connect(channels, patterns)
+		if nil == err {
+			if err = c.attach(ch); nil == err {
+				return
+			}
+			ch.Close(err)
+		}
+		if c.isClosed() {
+			c.giveUp(ErrClosed)
+			return
+		}
+		cause = err
+	}
+}
+
+// giveUp fails the held requests, and every later one, with err.
+func (c *Client) giveUp(err error) {
+	c.mutex.Lock()
+	queued := c.queued
+	c.queued, c.reconnecting = nil, false
+	if !c.closed {
+		c.failed = err
+	}
+	c.mutex.Unlock()
+
+	for _, request := range queued {
+		request.Future.resolve(nil, err)
+	}
+}
+
+// attach makes ch the client channel once the requests held back meanwhile
+// are written to it, it fails if ch dropped in between.
+func (c *Client) attach(ch netty.Channel) error {
+	for {
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			return ErrClosed
+		}
+		if !ch.IsActive() {
+			c.mutex.Unlock()
+			return ErrClosed
+		}
+
+		// later sends are held back as well until the queue is drained,
+		// so they stay behind the requests written here.
+		queued := c.queued
+		c.queued = nil
+		if 0 == len(queued) {
+			c.channel, c.reconnecting, c.failed = ch, false, nil
+			c.mutex.Unlock()
+			return nil
+		}
+		c.mutex.Unlock()
+
+		for i, request := range queued {
+			if err := ch.Write(request); nil != err {
+				c.requeue(queued[i:], err)
+				return err
+			}
+		}
+	}
+}
+
+// requeue puts requests back in front of the queue, transaction requests
+// fail instead since a transaction is never split across connections.
+func (c *Client) requeue(requests []*Request, err error) {
+	var kept []*Request
+	for _, request := range requests {
+		if request.noRetry {
+			request.Future.resolve(nil, err)
+		} else {
+			kept = append(kept, request)
+		}
+	}
+
+	c.mutex.Lock()
+	c.queued = append(kept, c.queued...)
+	c.mutex.Unlock()
+}
+
+func (c *Client) isClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.closed
+}
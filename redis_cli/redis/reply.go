@@ -0,0 +1,160 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// Nil is returned when the server replies with a null value.
+var Nil = errors.New("redis: nil")
+
+// Error is an error reply sent by the server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Prefix returns the error code, such as ERR, WRONGTYPE or MOVED.
+func (e Error) Prefix() string {
+	if i := strings.IndexByte(string(e), ' '); i >= 0 {
+		return string(e[:i])
+	}
+	return string(e)
+}
+
+func replyError(resp *redisgo.Resp) error {
+	switch resp.Kind {
+	case redisgo.ErrorKind, redisgo.BlobErrorKind:
+		return Error(resp.Data)
+	}
+	return nil
+}
+
+func unexpected(resp *redisgo.Resp) error {
+	return fmt.Errorf("redis: unexpected reply: %q", resp.String())
+}
+
+func toString(resp *redisgo.Resp) (string, error) {
+	if resp.Null {
+		return "", Nil
+	}
+	switch resp.Kind {
+	case redisgo.SimpleKind, redisgo.BlukKind, redisgo.IntegerKind, redisgo.DoubleKind, redisgo.BigNumberKind:
+		return resp.Data, nil
+	case redisgo.VerbatimKind:
+		// skip the format, e.g. "txt:"
+		return resp.Data[4:], nil
+	case redisgo.ErrorKind, redisgo.BlobErrorKind:
+		return "", Error(resp.Data)
+	}
+	return "", unexpected(resp)
+}
+
+func toInt64(resp *redisgo.Resp) (int64, error) {
+	s, err := toString(resp)
+	if nil != err {
+		return 0, err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func toFloat64(resp *redisgo.Resp) (float64, error) {
+	s, err := toString(resp)
+	if nil != err {
+		return 0, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func toBool(resp *redisgo.Resp) (bool, error) {
+	if resp.Null {
+		return false, nil
+	}
+	switch resp.Kind {
+	case redisgo.BooleanKind:
+		return resp.Data == "t", nil
+	case redisgo.IntegerKind:
+		return resp.Data != "0", nil
+	case redisgo.SimpleKind:
+		return resp.Data == "OK", nil
+	case redisgo.ErrorKind, redisgo.BlobErrorKind:
+		return false, Error(resp.Data)
+	}
+	return false, unexpected(resp)
+}
+
+// toPairs flattens RESP3 maps and RESP2 key/value arrays alike.
+func toPairs(resp *redisgo.Resp) ([]redisgo.Resp, error) {
+	switch resp.Kind {
+	case redisgo.MapKind:
+		flat := make([]redisgo.Resp, 0, 2*len(resp.Map))
+		for _, pair := range resp.Map {
+			flat = append(flat, pair.Key, pair.Value)
+		}
+		return flat, nil
+	case redisgo.ArrayKind, redisgo.SetKind:
+		if len(resp.Array)%2 != 0 {
+			return nil, unexpected(resp)
+		}
+		return resp.Array, nil
+	}
+	return nil, unexpected(resp)
+}
+
+func toStringMap(resp *redisgo.Resp) (map[string]string, error) {
+	flat, err := toPairs(resp)
+	if nil != err {
+		return nil, err
+	}
+	m := make(map[string]string, len(flat)/2)
+	for i := 0; i < len(flat); i += 2 {
+		key, err := toString(&flat[i])
+		if nil != err {
+			return nil, err
+		}
+		if m[key], err = toString(&flat[i+1]); nil != err {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func toStrings(resp *redisgo.Resp) ([]string, error) {
+	if resp.Null {
+		return nil, Nil
+	}
+	switch resp.Kind {
+	case redisgo.ArrayKind, redisgo.SetKind, redisgo.PushKind:
+	default:
+		return nil, unexpected(resp)
+	}
+	ss := make([]string, len(resp.Array))
+	for i := range resp.Array {
+		var err error
+		if ss[i], err = toString(&resp.Array[i]); nil != err && Nil != err {
+			return nil, err
+		}
+	}
+	return ss, nil
+}
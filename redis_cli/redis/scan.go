@@ -0,0 +1,184 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// Doer sends a command and waits for its reply, Client, ClusterClient and
+// SentinelClient all are one.
+type Doer interface {
+	Do(ctx context.Context, args ...interface{}) (*redisgo.Resp, error)
+}
+
+// ScanOption customizes a SCAN family command.
+type ScanOption func(args []interface{}) []interface{}
+
+// WithMatch only returns elements matching the glob-style pattern (MATCH).
+func WithMatch(pattern string) ScanOption {
+	return func(args []interface{}) []interface{} {
+		return append(args, "MATCH", pattern)
+	}
+}
+
+// WithCount hints how many elements the server looks at per call (COUNT).
+func WithCount(count int) ScanOption {
+	return func(args []interface{}) []interface{} {
+		return append(args, "COUNT", count)
+	}
+}
+
+// WithType only returns keys of the type, such as "hash" (TYPE), for SCAN.
+func WithType(keyType string) ScanOption {
+	return func(args []interface{}) []interface{} {
+		return append(args, "TYPE", keyType)
+	}
+}
+
+// ScanIterator walks a cursor of SCAN, HSCAN, SSCAN or ZSCAN, fetching the
+// next batch once the current one is used up. Elements may be returned
+// more than once if the collection changes meanwhile, as the server
+// guarantees nothing more.
+//
+//	it := client.Scan(redis.WithMatch("user:*"))
+//	for it.Next(ctx) {
+//		fmt.Println(it.Val())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ScanIterator struct {
+	doer    Doer
+	command []interface{}
+	options []interface{}
+	pairs   bool
+
+	cursor string
+	batch  []string
+	val    string
+	value  string
+	err    error
+}
+
+// NewScanIterator iterates command over doer, that is SCAN, or HSCAN, SSCAN
+// or ZSCAN of the collection at key.
+func NewScanIterator(doer Doer, command, key string, options ...ScanOption) *ScanIterator {
+	it := &ScanIterator{doer: doer, command: []interface{}{command}, cursor: "0"}
+	switch command = strings.ToUpper(command); command {
+	case "HSCAN", "ZSCAN":
+		it.pairs = true
+		fallthrough
+	case "SSCAN":
+		it.command = append(it.command, key)
+	}
+	for _, option := range options {
+		it.options = option(it.options)
+	}
+	return it
+}
+
+// Scan iterates the keys of the selected database.
+func (c *Client) Scan(options ...ScanOption) *ScanIterator {
+	return NewScanIterator(c, "SCAN", "", options...)
+}
+
+// HScan iterates the fields of the hash at key, Value is the field value.
+func (c *Client) HScan(key string, options ...ScanOption) *ScanIterator {
+	return NewScanIterator(c, "HSCAN", key, options...)
+}
+
+// SScan iterates the members of the set at key.
+func (c *Client) SScan(key string, options ...ScanOption) *ScanIterator {
+	return NewScanIterator(c, "SSCAN", key, options...)
+}
+
+// ZScan iterates the members of the sorted set at key, Value is the score.
+func (c *Client) ZScan(key string, options ...ScanOption) *ScanIterator {
+	return NewScanIterator(c, "ZSCAN", key, options...)
+}
+
+// Next advances to the next element, it returns false once the iteration
+// is complete or failed, see Err.
+func (it *ScanIterator) Next(ctx context.Context) bool {
+
+	for 0 == len(it.batch) {
+		// the server returns cursor 0 with the last batch.
+		if "" == it.cursor || nil != it.err {
+			return false
+		}
+
+		args := append(append(append([]interface{}{}, it.command...), it.cursor), it.options...)
+		resp, err := it.doer.Do(ctx, args...)
+		if nil == err {
+			err = it.parse(resp)
+		}
+		if nil != err {
+			it.err = err
+			return false
+		}
+	}
+
+	it.val, it.batch = it.batch[0], it.batch[1:]
+	if it.pairs {
+		it.value, it.batch = it.batch[0], it.batch[1:]
+	}
+	return true
+}
+
+func (it *ScanIterator) parse(resp *redisgo.Resp) error {
+	if resp.Kind != redisgo.ArrayKind || len(resp.Array) != 2 {
+		return unexpected(resp)
+	}
+
+	cursor, err := toString(&resp.Array[0])
+	if nil != err {
+		return err
+	}
+	batch, err := toStrings(&resp.Array[1])
+	if nil != err {
+		return err
+	}
+	if it.pairs && len(batch)%2 != 0 {
+		return unexpected(resp)
+	}
+
+	if it.cursor = cursor; "0" == cursor {
+		it.cursor = ""
+	}
+	it.batch = batch
+	return nil
+}
+
+// Val returns the current key, field or member.
+func (it *ScanIterator) Val() string {
+	return it.val
+}
+
+// Value returns the value of the current field for HSCAN, or the score of
+// the current member for ZSCAN.
+func (it *ScanIterator) Value() string {
+	return it.value
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *ScanIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanIterator(t *testing.T) {
+
+	replies := map[string]string{
+		"SCAN 0 MATCH user:* COUNT 2":  "*2\r\n$2\r\n17\r\n*2\r\n$6\r\nuser:1\r\n$6\r\nuser:2\r\n",
+		"SCAN 17 MATCH user:* COUNT 2": "*2\r\n$2\r\n42\r\n*0\r\n",
+		"SCAN 42 MATCH user:* COUNT 2": "*2\r\n$1\r\n0\r\n*1\r\n$6\r\nuser:3\r\n",
+		"HSCAN h 0":                    "*2\r\n$1\r\n0\r\n*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n",
+		"SSCAN s 0":                    "*2\r\n$1\r\n0\r\n*1\r\n$1\r\nx\r\n",
+		"ZSCAN z 0":                    "*2\r\n$1\r\n0\r\n*3\r\n$1\r\nx\r\n$1\r\n1\r\n$1\r\ny\r\n",
+	}
+	server := newTestServer(t, func(args []string) string {
+		if reply, ok := replies[strings.Join(args, " ")]; ok {
+			return reply
+		}
+		return "-ERR unexpected\r\n"
+	})
+
+	client, err := Dial(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collect := func(it *ScanIterator) ([]string, error) {
+		var got []string
+		for it.Next(ctx) {
+			got = append(got, it.Val())
+			if it.pairs {
+				got = append(got, it.Value())
+			}
+		}
+		return got, it.Err()
+	}
+
+	tests := []struct {
+		name string
+		it   *ScanIterator
+		want []string
+	}{
+		{"scan", client.Scan(WithMatch("user:*"), WithCount(2)), []string{"user:1", "user:2", "user:3"}},
+		{"hscan", client.HScan("h"), []string{"a", "1", "b", "2"}},
+		{"sscan", client.SScan("s"), []string{"x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := collect(tt.it)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// an odd number of elements is not a list of pairs.
+	if _, err := collect(client.ZScan("z")); nil == err {
+		t.Error("ZSCAN with an odd reply expect error")
+	}
+}
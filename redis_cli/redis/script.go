@@ -0,0 +1,69 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// Script is a Lua script run by its SHA1 digest, so that the source is
+// only sent when the server does not have it cached yet.
+type Script struct {
+	src  string
+	hash string
+}
+
+// NewScript creates a script from its Lua source.
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{src: src, hash: hex.EncodeToString(sum[:])}
+}
+
+// Hash returns the SHA1 digest EVALSHA refers to the script by.
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// Load caches the script on the server with SCRIPT LOAD.
+func (s *Script) Load(ctx context.Context, doer Doer) error {
+	_, err := doer.Do(ctx, "SCRIPT", "LOAD", s.src)
+	return err
+}
+
+// Run runs the script with EVALSHA and falls back to EVAL, which caches
+// it as well, if the server replies NOSCRIPT.
+func (s *Script) Run(ctx context.Context, doer Doer, keys []string, args ...interface{}) (*redisgo.Resp, error) {
+	resp, err := doer.Do(ctx, s.args("EVALSHA", s.hash, keys, args)...)
+
+	var e Error
+	if errors.As(err, &e) && "NOSCRIPT" == e.Prefix() {
+		resp, err = doer.Do(ctx, s.args("EVAL", s.src, keys, args)...)
+	}
+	return resp, err
+}
+
+func (s *Script) args(command, script string, keys []string, args []interface{}) []interface{} {
+	eval := make([]interface{}, 0, 3+len(keys)+len(args))
+	eval = append(eval, command, script, len(keys))
+	eval = append(eval, strings2args(keys)...)
+	return append(eval, args...)
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScript(t *testing.T) {
+
+	script := NewScript("return redis.call('GET', KEYS[1])")
+	if want := "d3c21d0c2b9ca22f82737626a27bcaf5d288f99f"; script.Hash() != want {
+		t.Errorf("Hash() = %s, want %s", script.Hash(), want)
+	}
+
+	var mutex sync.Mutex
+	var loaded bool
+	server := newTestServer(t, func(args []string) string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch args[0] {
+		case "EVALSHA":
+			if !loaded {
+				return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+			}
+		case "EVAL":
+			loaded = true
+		}
+		return "$5\r\nvalue\r\n"
+	})
+
+	client, err := Dial(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		resp, err := script.Run(ctx, client, []string{"k"}, "a", 1)
+		if nil != err || resp.Data != "value" {
+			t.Fatalf("Run() = %v, %v", resp, err)
+		}
+	}
+
+	want := []string{
+		"EVALSHA " + script.Hash() + " 1 k a 1",
+		"EVAL return redis.call('GET', KEYS[1]) 1 k a 1",
+		"EVALSHA " + script.Hash() + " 1 k a 1",
+	}
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if !reflect.DeepEqual(server.commands, want) {
+		t.Errorf("commands = %q, want %q", server.commands, want)
+	}
+}
@@ -0,0 +1,287 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// switchMasterChannel is where sentinels announce a completed failover.
+const switchMasterChannel = "+switch-master"
+
+// ErrNoSentinels is returned when no sentinel could resolve the master.
+var ErrNoSentinels = errors.New("redis: no sentinel reachable")
+
+// SentinelClient is a connection to the master of a sentinel monitored
+// group, it follows failovers announced by the sentinels and re-resolves
+// the master whenever the connection drops.
+type SentinelClient struct {
+	name      string
+	options   []DialOption
+	sentinel  []DialOption
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// held while the master is resolved and dialed, so that only one
+	// caller does it. mutex is not held meanwhile, failover events and
+	// Addr are not held up by the network.
+	dialMutex sync.Mutex
+
+	mutex     sync.Mutex
+	sentinels []string
+	master    *Client
+	addr      string
+	onSwitch  func(addr string)
+}
+
+// DialSentinel resolves the master called name through the sentinels and
+// connects to it, options apply to the master connection and those given
+// with WithSentinelOptions to the sentinel connections.
+func DialSentinel(name string, sentinels []string, options ...DialOption) (*SentinelClient, error) {
+	var o dialOptions
+	for _, option := range options {
+		option(&o)
+	}
+
+	s := &SentinelClient{name: name, sentinels: append([]string(nil), sentinels...), options: options,
+		sentinel: o.sentinel, closed: make(chan struct{})}
+	if _, err := s.client(); nil != err {
+		return nil, err
+	}
+	go s.watch()
+	return s, nil
+}
+
+// OnSwitch registers fn to be called with the new master address after
+// a failover, it must not block.
+func (s *SentinelClient) OnSwitch(fn func(addr string)) {
+	s.mutex.Lock()
+	s.onSwitch = fn
+	s.mutex.Unlock()
+}
+
+// Addr returns the address of the current master.
+func (s *SentinelClient) Addr() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.addr
+}
+
+// Close closes the master connection and stops watching the sentinels.
+func (s *SentinelClient) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+
+	s.mutex.Lock()
+	master := s.master
+	s.master = nil
+	s.mutex.Unlock()
+
+	if nil != master {
+		master.Close()
+	}
+	return nil
+}
+
+// Send writes a command to the current master without waiting for the reply.
+func (s *SentinelClient) Send(args ...interface{}) *Future {
+	client, err := s.client()
+	if nil != err {
+		future := newFuture()
+		future.resolve(nil, err)
+		return future
+	}
+	return client.Send(args...)
+}
+
+// Do sends a command to the current master and waits for its reply.
+func (s *SentinelClient) Do(ctx context.Context, args ...interface{}) (*redisgo.Resp, error) {
+	return wait(ctx, s.Send(args...))
+}
+
+// client returns the master connection, resolving and dialing the master
+// again if the previous connection is gone.
+func (s *SentinelClient) client() (*Client, error) {
+	if master, err := s.active(); nil != master || nil != err {
+		return master, err
+	}
+
+	s.dialMutex.Lock()
+	defer s.dialMutex.Unlock()
+
+	// another caller may have dialed, or closed the client, meanwhile.
+	if master, err := s.active(); nil != master || nil != err {
+		return master, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	addr, err := s.resolve(ctx)
+	if nil != err {
+		return nil, err
+	}
+
+	master, err := Dial(addr, s.options...)
+	if nil != err {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	select {
+	case <-s.closed:
+		s.mutex.Unlock()
+		master.Close()
+		return nil, ErrClosed
+	default:
+	}
+	old := s.master
+	s.master, s.addr = master, addr
+	s.mutex.Unlock()
+
+	if nil != old {
+		old.Close()
+	}
+	return master, nil
+}
+
+// active returns the master connection if it is still up, or nil if it
+// must be dialed again. Once closed it fails with ErrClosed.
+func (s *SentinelClient) active() (*Client, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.closed:
+		return nil, ErrClosed
+	default:
+	}
+	if nil != s.master && s.master.Channel().IsActive() {
+		return s.master, nil
+	}
+	return nil, nil
+}
+
+// resolve asks each sentinel in turn for the master address, the sentinel
+// that answers is moved to the front so it is asked first next time.
+func (s *SentinelClient) resolve(ctx context.Context) (string, error) {
+	s.mutex.Lock()
+	sentinels := append([]string(nil), s.sentinels...)
+	s.mutex.Unlock()
+
+	err := ErrNoSentinels
+	for _, sentinel := range sentinels {
+		var addr string
+		if addr, err = masterAddr(ctx, sentinel, s.name, s.sentinel); nil != err {
+			continue
+		}
+
+		s.mutex.Lock()
+		for i := range s.sentinels {
+			if s.sentinels[i] == sentinel {
+				copy(s.sentinels[1:i+1], s.sentinels[:i])
+				s.sentinels[0] = sentinel
+				break
+			}
+		}
+		s.mutex.Unlock()
+		return addr, nil
+	}
+	return "", err
+}
+
+// masterAddr queries SENTINEL get-master-addr-by-name on one sentinel.
+func masterAddr(ctx context.Context, sentinel, name string, options []DialOption) (string, error) {
+	client, err := Dial(sentinel, options...)
+	if nil != err {
+		return "", err
+	}
+	defer client.Close()
+
+	resp, err := client.Do(ctx, "SENTINEL", "get-master-addr-by-name", name)
+	if nil != err {
+		return "", err
+	}
+	if resp.Null || len(resp.Array) != 2 {
+		return "", fmt.Errorf("redis: sentinel %s does not know master %q", sentinel, name)
+	}
+	return net.JoinHostPort(resp.Array[0].Data, resp.Array[1].Data), nil
+}
+
+// watch subscribes to failover announcements, moving on to the next
+// sentinel whenever the subscribed one goes away.
+func (s *SentinelClient) watch() {
+	for i := 0; ; i++ {
+		s.mutex.Lock()
+		sentinel := s.sentinels[i%len(s.sentinels)]
+		s.mutex.Unlock()
+
+		if client, err := Dial(sentinel, s.sentinel...); nil == err {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			err = client.Subscribe(ctx, s.switchMaster, switchMasterChannel)
+			cancel()
+
+			if nil == err {
+				select {
+				case <-client.Channel().Context().Done():
+				case <-s.closed:
+				}
+			}
+			client.Close()
+		}
+
+		select {
+		case <-s.closed:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// switchMaster handles "<name> <old ip> <old port> <new ip> <new port>",
+// the master connection is dropped so the next command re-resolves it.
+func (s *SentinelClient) switchMaster(msg *Message) {
+	fields := strings.Fields(msg.Payload)
+	if len(fields) != 5 || fields[0] != s.name {
+		return
+	}
+	addr := net.JoinHostPort(fields[3], fields[4])
+
+	s.mutex.Lock()
+	if addr == s.addr {
+		s.mutex.Unlock()
+		return
+	}
+	master, onSwitch := s.master, s.onSwitch
+	s.master = nil
+	s.mutex.Unlock()
+
+	// pending commands on the old master fail with ErrClosed.
+	if nil != master {
+		go master.Close()
+	}
+	if nil != onSwitch {
+		onSwitch(addr)
+	}
+}
@@ -0,0 +1,212 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSentinelClient(t *testing.T) {
+
+	// masters echo their own address.
+	var masters [2]*testServer
+	var mutex sync.Mutex
+	for i := range masters {
+		i := i
+		mutex.Lock()
+		masters[i] = newTestServer(t, func(args []string) string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return "+" + masters[i].addr + "\r\n"
+		})
+		mutex.Unlock()
+	}
+
+	current := masters[0].addr
+	sentinel := newTestServer(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "SENTINEL":
+			if args[2] != "mymaster" {
+				return "*-1\r\n"
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			host, port, _ := net.SplitHostPort(current)
+			return "*2\r\n$" + strconv.Itoa(len(host)) + "\r\n" + host + "\r\n$" + strconv.Itoa(len(port)) + "\r\n" + port + "\r\n"
+		case "SUBSCRIBE":
+			return pubsubFrame(t, "subscribe", args[1], ":1").String()
+		}
+		return "-ERR unknown command\r\n"
+	})
+
+	// a sentinel that refuses connections is skipped.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	down := l.Addr().String()
+	l.Close()
+
+	if _, err := DialSentinel("unknown", []string{down, sentinel.addr}); nil == err {
+		t.Errorf("DialSentinel() with unknown master expect error")
+	}
+
+	client, err := DialSentinel("mymaster", []string{down, sentinel.addr})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	switched := make(chan string, 1)
+	client.OnSwitch(func(addr string) { switched <- addr })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	expect := func(want string) {
+		t.Helper()
+		resp, err := client.Do(ctx, "PING")
+		if nil != err {
+			t.Fatal(err)
+		}
+		if resp.Data != want {
+			t.Errorf("served by %s, want %s", resp.Data, want)
+		}
+	}
+	expect(masters[0].addr)
+
+	for !sentinel.served("SUBSCRIBE " + switchMasterChannel) {
+		select {
+		case <-ctx.Done():
+			t.Fatal("sentinel not subscribed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// failover announced by the sentinel.
+	mutex.Lock()
+	current = masters[1].addr
+	mutex.Unlock()
+	oldHost, oldPort, _ := net.SplitHostPort(masters[0].addr)
+	newHost, newPort, _ := net.SplitHostPort(masters[1].addr)
+	client.switchMaster(&Message{Channel: switchMasterChannel, Payload: strings.Join([]string{"mymaster", oldHost, oldPort, newHost, newPort}, " ")})
+
+	select {
+	case addr := <-switched:
+		if addr != masters[1].addr {
+			t.Errorf("OnSwitch() addr = %s, want %s", addr, masters[1].addr)
+		}
+	case <-ctx.Done():
+		t.Fatal("OnSwitch() not called")
+	}
+	expect(masters[1].addr)
+
+	// a dropped connection is re-resolved.
+	mutex.Lock()
+	current = masters[0].addr
+	mutex.Unlock()
+	client.master.Channel().Close(errors.New("connection reset"))
+	<-client.master.Channel().Context().Done()
+	expect(masters[0].addr)
+
+	// a closed client fails without asking the sentinels.
+	client.Close()
+	sentinel.mutex.Lock()
+	asked := len(sentinel.commands)
+	sentinel.mutex.Unlock()
+	if _, err := client.Do(ctx, "PING"); err != ErrClosed {
+		t.Errorf("Do() after Close error = %v, want %v", err, ErrClosed)
+	}
+	sentinel.mutex.Lock()
+	if len(sentinel.commands) != asked {
+		t.Errorf("sentinel served %q after Close", sentinel.commands[asked:])
+	}
+	sentinel.mutex.Unlock()
+}
+
+func TestSentinelClient_Options(t *testing.T) {
+
+	master := newTestServer(t, func(args []string) string { return "+OK\r\n" })
+
+	// resolving blocks once release is set, until it is closed.
+	var mutex sync.Mutex
+	var release chan struct{}
+	resolving := make(chan struct{}, 1)
+	sentinel := newTestServer(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "SENTINEL":
+			mutex.Lock()
+			wait := release
+			mutex.Unlock()
+			if nil != wait {
+				resolving <- struct{}{}
+				<-wait
+			}
+			host, port, _ := net.SplitHostPort(master.addr)
+			return "*2\r\n$" + strconv.Itoa(len(host)) + "\r\n" + host + "\r\n$" + strconv.Itoa(len(port)) + "\r\n" + port + "\r\n"
+		case "SUBSCRIBE":
+			return pubsubFrame(t, "subscribe", args[1], ":1").String()
+		}
+		return "+OK\r\n"
+	})
+
+	client, err := DialSentinel("mymaster", []string{sentinel.addr},
+		WithAuth("", "master-secret"), WithSentinelOptions(WithAuth("", "sentinel-secret")))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if !sentinel.served("AUTH sentinel-secret") || sentinel.served("AUTH master-secret") {
+		t.Errorf("sentinel served %q, want AUTH sentinel-secret only", sentinel.commands)
+	}
+	if !master.served("AUTH master-secret") || master.served("AUTH sentinel-secret") {
+		t.Errorf("master served %q, want AUTH master-secret only", master.commands)
+	}
+
+	// the sentinel lock is not held while the master is resolved again.
+	mutex.Lock()
+	release = make(chan struct{})
+	mutex.Unlock()
+	client.master.Channel().Close(errors.New("connection reset"))
+	<-client.master.Channel().Context().Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Do(ctx, "PING")
+		done <- err
+	}()
+
+	select {
+	case <-resolving:
+	case <-ctx.Done():
+		t.Fatal("master not resolved again")
+	}
+
+	addr := make(chan string, 1)
+	go func() { addr <- client.Addr() }()
+	select {
+	case got := <-addr:
+		if got != master.addr {
+			t.Errorf("Addr() = %s, want %s", got, master.addr)
+		}
+	case <-time.After(time.Second):
+		t.Error("Addr() blocked while resolving")
+	}
+
+	mutex.Lock()
+	close(release)
+	release = nil
+	mutex.Unlock()
+	if err := <-done; nil != err {
+		t.Fatal(err)
+	}
+}
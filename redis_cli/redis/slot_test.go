@@ -0,0 +1,28 @@
+package redis
+
+import "testing"
+
+func TestSlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"", 0},
+		{"foo", 12182},
+		{"bar", 5061},
+		{"123456789", 0x31c3 % SlotCount},
+		{"{user1000}.following", Slot("user1000")},
+		{"{user1000}.followers", Slot("user1000")},
+		{"foo{{bar}}zap", Slot("{bar")},
+		{"foo{bar}{zap}", Slot("bar")},
+	}
+	for _, tt := range tests {
+		if got := Slot(tt.key); got != tt.want {
+			t.Errorf("Slot(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+
+	if got, want := Slot("foo{}{bar}"), int(crc16("foo{}{bar}"))%SlotCount; got != want {
+		t.Errorf("Slot() with empty hash tag = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"crypto/tls"
+
+	nettls "github.com/go-netty/go-netty-transport/tls"
+	"github.com/go-netty/go-netty/transport"
+)
+
+// NewTLSTransport creates a transport factory that speaks TLS over tcp with
+// config, on top of the go-netty tls transport.
+func NewTLSTransport(config *tls.Config) transport.Factory {
+	if nil == config {
+		config = &tls.Config{}
+	}
+	return &tlsFactory{Factory: nettls.New(), config: config}
+}
+
+// tlsFactory hands config to the go-netty tls transport on every connect
+// and listen.
+type tlsFactory struct {
+	transport.Factory
+	config *tls.Config
+}
+
+func (f *tlsFactory) Connect(options *transport.Options) (transport.Transport, error) {
+	// verify the server against the host it is dialed by.
+	config := f.config.Clone()
+	if "" == config.ServerName && !config.InsecureSkipVerify {
+		config.ServerName = options.Address.Hostname()
+	}
+	if err := nettls.WithOptions(&nettls.Options{Config: config})(options); nil != err {
+		return nil, err
+	}
+	return f.Factory.Connect(options)
+}
+
+func (f *tlsFactory) Listen(options *transport.Options) (transport.Acceptor, error) {
+	if err := nettls.WithOptions(&nettls.Options{Config: f.config})(options); nil != err {
+		return nil, err
+	}
+	return f.Factory.Listen(options)
+}
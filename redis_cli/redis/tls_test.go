@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-netty/go-netty"
+)
+
+// selfSigned creates a certificate for 127.0.0.1 and a pool trusting it.
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "redis"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if nil != err {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestDial_TLS(t *testing.T) {
+
+	cert, pool := selfSigned(t)
+	server := newTestServer(t, func(args []string) string {
+		return "+PONG\r\n"
+	}, netty.WithTransport(NewTLSTransport(&tls.Config{Certificates: []tls.Certificate{cert}})))
+
+	client, err := Dial(server.addr, WithTLS(&tls.Config{RootCAs: pool}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if resp, err := client.Do(ctx, "PING"); nil != err || resp.Data != "PONG" {
+		t.Errorf("Do(PING) = %v, %v", resp, err)
+	}
+
+	// the server certificate is not trusted without the pool.
+	if _, err := Dial(server.addr, WithTLS(&tls.Config{})); nil == err {
+		t.Errorf("Dial() with untrusted certificate expect error")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// InvalidateChannel carries the invalidations of connections that track
+// keys with REDIRECT, the way client-side caching works over RESP2.
+const InvalidateChannel = "__redis__:invalidate"
+
+// Invalidation tells a client-side cache which keys changed. Keys is nil
+// when everything must be dropped: after FLUSHALL, or once the connection
+// is lost and notifications may have been missed.
+type Invalidation struct {
+	Keys []string
+}
+
+func parseInvalidation(resp *redisgo.Resp) (*Invalidation, bool) {
+	var keys *redisgo.Resp
+	switch {
+	case resp.Kind == redisgo.PushKind && len(resp.Array) == 2 && strings.EqualFold(resp.Array[0].Data, "invalidate"):
+		keys = &resp.Array[1]
+	case resp.Kind == redisgo.PushKind && pubsubKind(resp) == "message" && resp.Array[1].Data == InvalidateChannel:
+		keys = &resp.Array[2]
+	default:
+		return nil, false
+	}
+
+	invalidation := &Invalidation{}
+	if !keys.Null {
+		for i := range keys.Array {
+			invalidation.Keys = append(invalidation.Keys, keys.Array[i].Data)
+		}
+	}
+	return invalidation, true
+}
+
+// TrackingHandler passes the invalidations of client-side caching to a
+// callback, both RESP3 invalidate pushes and messages on InvalidateChannel.
+// It must sit in front of a PubSubHandler. The callback runs on the channel
+// read loop, so it must not block.
+type TrackingHandler struct {
+	mutex sync.RWMutex
+	fn    func(*Invalidation)
+}
+
+// NewTrackingHandler creates a handler, fn may be nil until OnInvalidate.
+func NewTrackingHandler(fn func(*Invalidation)) *TrackingHandler {
+	return &TrackingHandler{fn: fn}
+}
+
+// OnInvalidate sets the callback, nil passes invalidations on to the next handler.
+func (t *TrackingHandler) OnInvalidate(fn func(*Invalidation)) {
+	t.mutex.Lock()
+	t.fn = fn
+	t.mutex.Unlock()
+}
+
+func (t *TrackingHandler) callback() func(*Invalidation) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.fn
+}
+
+func (t *TrackingHandler) HandleRead(ctx netty.InboundContext, message netty.Message) {
+
+	fn := t.callback()
+	if nil == fn {
+		ctx.HandleRead(message)
+		return
+	}
+
+	invalidation, ok := parseInvalidation(message.(*redisgo.Resp))
+	if !ok {
+		ctx.HandleRead(message)
+		return
+	}
+	fn(invalidation)
+}
+
+func (t *TrackingHandler) HandleInactive(ctx netty.InactiveContext, ex netty.Exception) {
+	// nothing is tracked on the next connection until tracking is on again.
+	if fn := t.callback(); nil != fn {
+		fn(&Invalidation{})
+	}
+	ctx.HandleInactive(ex)
+}
+
+// Track turns on client-side caching, keys read on this connection are
+// reported to fn once they change. options are passed to CLIENT TRACKING
+// ON, such as "BCAST" or "PREFIX", "user:". The server only pushes
+// invalidations over RESP3, see WithProtocol; over RESP2 pass "REDIRECT"
+// and the id of a connection that calls OnInvalidate and subscribes to
+// InvalidateChannel. Tracking is turned on again after reconnecting.
+func (c *Client) Track(ctx context.Context, fn func(*Invalidation), options ...interface{}) error {
+	c.tracking.OnInvalidate(fn)
+
+	command := append([]interface{}{"CLIENT", "TRACKING", "ON"}, options...)
+	if _, err := c.Do(ctx, command...); nil != err {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.track = command
+	c.mutex.Unlock()
+	return nil
+}
+
+// Untrack turns client-side caching off.
+func (c *Client) Untrack(ctx context.Context) error {
+	c.mutex.Lock()
+	c.track = nil
+	c.mutex.Unlock()
+
+	_, err := c.Do(ctx, "CLIENT", "TRACKING", "OFF")
+	c.tracking.OnInvalidate(nil)
+	return err
+}
+
+// OnInvalidate passes the invalidations arriving on this connection to fn,
+// for connections other clients redirect their invalidations to.
+func (c *Client) OnInvalidate(fn func(*Invalidation)) {
+	c.tracking.OnInvalidate(fn)
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-netty/go-netty"
+)
+
+func TestTrackingHandler(t *testing.T) {
+
+	var got []*Invalidation
+	unclaimed, handler := &readSink{}, NewTrackingHandler(func(invalidation *Invalidation) {
+		got = append(got, invalidation)
+	})
+	pl := netty.NewPipeline().AddLast(handler, unclaimed)
+
+	pl.FireChannelRead(decodeResp(t, ">2\r\n$10\r\ninvalidate\r\n*2\r\n$1\r\na\r\n$1\r\nb\r\n"))
+	pl.FireChannelRead(decodeResp(t, ">2\r\n$10\r\ninvalidate\r\n_\r\n"))
+	pl.FireChannelRead(asPush(decodeResp(t, "*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*1\r\n$1\r\nc\r\n")))
+	pl.FireChannelRead(pushFrame(t, "message", "news", "d"))
+
+	// not subscribed, the pipeline passes it on as an unsolicited reply.
+	pl.FireChannelRead(pubsubFrame(t, "message", "__redis__:invalidate", "e"))
+	pl.FireChannelInactive(errors.New("connection reset"))
+
+	want := []*Invalidation{{Keys: []string{"a", "b"}}, {}, {Keys: []string{"c"}}, {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalidations = %+v, want %+v", got, want)
+	}
+	if len(unclaimed.messages) != 2 {
+		t.Errorf("unclaimed = %d, want 2", len(unclaimed.messages))
+	}
+}
+
+func TestClient_Track(t *testing.T) {
+
+	server := newTestServer(t, func(args []string) string {
+		switch args[0] {
+		case "HELLO":
+			return "%1\r\n$5\r\nproto\r\n:3\r\n"
+		case "CLIENT":
+			if args[2] == "ON" {
+				return "+OK\r\n>2\r\n$10\r\ninvalidate\r\n*1\r\n$1\r\nk\r\n"
+			}
+		}
+		return "+OK\r\n"
+	})
+
+	client, err := Dial(server.addr, WithProtocol(3))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	invalidations := make(chan *Invalidation, 2)
+	if err = client.Track(ctx, func(invalidation *Invalidation) { invalidations <- invalidation }, "BCAST"); nil != err {
+		t.Fatal(err)
+	}
+	if !server.served("HELLO 3") || !server.served("CLIENT TRACKING ON BCAST") {
+		t.Error("HELLO 3 or CLIENT TRACKING ON BCAST not sent")
+	}
+
+	// a dropped connection invalidates everything.
+	client.Channel().Close(errors.New("dropped"))
+	for _, want := range []*Invalidation{{Keys: []string{"k"}}, {}} {
+		select {
+		case got := <-invalidations:
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("invalidation = %+v, want %+v", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatal("no invalidation received")
+		}
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// ErrTxFailed is returned by Exec when EXEC replied null because a watched
+// key changed, nothing was executed.
+var ErrTxFailed = errors.New("redis: transaction failed, a watched key changed")
+
+// ExecAbortError is returned by Exec when the server refused to queue a
+// command, such as one with a wrong number of arguments, and discarded the
+// transaction as a whole.
+type ExecAbortError struct {
+	// Err is the EXECABORT reply.
+	Err Error
+
+	// Errors holds the reply to queueing each command, nil for the
+	// commands that were queued.
+	Errors []error
+}
+
+func (e *ExecAbortError) Error() string {
+	return string(e.Err)
+}
+
+func (e *ExecAbortError) Unwrap() error {
+	return e.Err
+}
+
+// Tx is a MULTI/EXEC transaction. Commands are queued locally and written
+// together with MULTI and EXEC, so that commands other goroutines send on
+// the same client do not end up in the transaction.
+type Tx struct {
+	client   *Client
+	commands [][]interface{}
+}
+
+// Multi starts a transaction.
+func (c *Client) Multi() *Tx {
+	return &Tx{client: c}
+}
+
+// Queue adds a command to the transaction.
+func (tx *Tx) Queue(args ...interface{}) {
+	tx.commands = append(tx.commands, args)
+}
+
+// Discard drops the queued commands.
+func (tx *Tx) Discard() {
+	tx.commands = nil
+}
+
+// Do runs a command right away, outside of the transaction, such as a read
+// of a watched key.
+func (tx *Tx) Do(ctx context.Context, args ...interface{}) (*redisgo.Resp, error) {
+	return tx.client.Do(ctx, args...)
+}
+
+// Exec runs the queued commands and returns their replies in order, which
+// may be error replies of commands that failed while executing. It fails
+// with an ExecAbortError if a command could not be queued, and with
+// ErrTxFailed if a watched key changed.
+func (tx *Tx) Exec(ctx context.Context) ([]*redisgo.Resp, error) {
+
+	requests := make([]*Request, 0, len(tx.commands)+2)
+	requests = append(requests, NewRequest(Args("MULTI")...))
+	for _, command := range tx.commands {
+		requests = append(requests, NewRequest(Args(command...)...))
+	}
+	requests = append(requests, NewRequest(Args("EXEC")...))
+	tx.commands = nil
+
+	for _, request := range requests {
+		request.noRetry = true
+	}
+	tx.client.sendBatch(requests...)
+
+	if _, err := wait(ctx, requests[0].Future); nil != err {
+		return nil, err
+	}
+
+	// every command is answered with QUEUED or the reason it was refused.
+	queued := requests[1 : len(requests)-1]
+	errs := make([]error, len(queued))
+	for i, request := range queued {
+		if _, err := wait(ctx, request.Future); nil != err {
+			var e Error
+			if !errors.As(err, &e) {
+				return nil, err
+			}
+			errs[i] = err
+		}
+	}
+
+	resp, err := wait(ctx, requests[len(requests)-1].Future)
+	var e Error
+	if errors.As(err, &e) && "EXECABORT" == e.Prefix() {
+		return nil, &ExecAbortError{Err: e, Errors: errs}
+	}
+	if nil != err {
+		return nil, err
+	}
+	if resp.Null {
+		return nil, ErrTxFailed
+	}
+	if resp.Kind != redisgo.ArrayKind || len(resp.Array) != len(queued) {
+		return nil, unexpected(resp)
+	}
+
+	replies := make([]*redisgo.Resp, len(resp.Array))
+	for i := range resp.Array {
+		replies[i] = &resp.Array[i]
+	}
+	return replies, nil
+}
+
+// Watch runs fn as an optimistic transaction on keys. It watches the keys
+// on a connection of its own, fn reads them with tx.Do and queues its
+// writes, and the transaction is executed unless fn fails. If a watched key
+// changed meanwhile everything runs again, up to retries times, before
+// ErrTxFailed is returned.
+func (c *Client) Watch(ctx context.Context, retries int, fn func(tx *Tx) error, keys ...string) error {
+
+	// WATCH state belongs to the connection, it must not be shared.
+	conn, err := Dial(c.addr, c.dialOptions...)
+	if nil != err {
+		return err
+	}
+	defer conn.Close()
+	return watch(ctx, conn, retries, fn, keys)
+}
+
+func watch(ctx context.Context, conn *Client, retries int, fn func(tx *Tx) error, keys []string) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if _, err = conn.Do(ctx, append([]interface{}{"WATCH"}, strings2args(keys)...)...); nil != err {
+			return err
+		}
+
+		tx := conn.Multi()
+		if err = fn(tx); nil != err {
+			conn.Do(ctx, "UNWATCH")
+			return err
+		}
+
+		if _, err = tx.Exec(ctx); ErrTxFailed != err {
+			return err
+		}
+	}
+	return ErrTxFailed
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTx(t *testing.T) {
+
+	var mutex sync.Mutex
+	var multi, aborted bool
+	var queued, watchFailures int
+	server := newTestServer(t, func(args []string) string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch args[0] {
+		case "MULTI":
+			multi, aborted, queued = true, false, 0
+			return "+OK\r\n"
+		case "EXEC":
+			multi = false
+			switch {
+			case aborted:
+				return "-EXECABORT Transaction discarded because of previous errors.\r\n"
+			case watchFailures > 0:
+				watchFailures--
+				return "*-1\r\n"
+			}
+			reply := "*" + string(rune('0'+queued)) + "\r\n"
+			for i := 0; i < queued; i++ {
+				reply += ":1\r\n"
+			}
+			return reply
+		case "WATCH", "UNWATCH":
+			return "+OK\r\n"
+		case "BAD":
+			aborted = true
+			return "-ERR unknown command 'BAD'\r\n"
+		}
+		if multi {
+			queued++
+			return "+QUEUED\r\n"
+		}
+		return "$1\r\n5\r\n"
+	})
+
+	client, err := Dial(server.addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx := client.Multi()
+	tx.Queue("INCR", "a")
+	tx.Queue("INCR", "b")
+	replies, err := tx.Exec(ctx)
+	if nil != err || len(replies) != 2 || replies[1].Data != "1" {
+		t.Fatalf("Exec() = %v, %v", replies, err)
+	}
+
+	tx.Queue("INCR", "a")
+	tx.Queue("BAD")
+	_, err = tx.Exec(ctx)
+	var abort *ExecAbortError
+	if !errors.As(err, &abort) || abort.Err.Prefix() != "EXECABORT" {
+		t.Fatalf("Exec() error = %v, want ExecAbortError", err)
+	}
+	if len(abort.Errors) != 2 || nil != abort.Errors[0] || nil == abort.Errors[1] {
+		t.Errorf("queue errors = %v", abort.Errors)
+	}
+
+	// optimistic locking runs again while a watched key changes.
+	mutex.Lock()
+	watchFailures = 2
+	mutex.Unlock()
+
+	var attempts int
+	err = client.Watch(ctx, 3, func(tx *Tx) error {
+		attempts++
+		resp, err := tx.Do(ctx, "GET", "a")
+		if nil != err {
+			return err
+		}
+		tx.Queue("SET", "a", resp.Data+"0")
+		return nil
+	}, "a")
+	if nil != err || attempts != 3 {
+		t.Errorf("Watch() = %v after %d attempts, want nil after 3", err, attempts)
+	}
+	if !server.served("SET a 50") || !server.served("WATCH a") {
+		t.Error("SET a 50 or WATCH a not sent")
+	}
+
+	mutex.Lock()
+	watchFailures = 2
+	mutex.Unlock()
+	if err = client.Watch(ctx, 1, func(tx *Tx) error { return nil }, "a"); err != ErrTxFailed {
+		t.Errorf("Watch() error = %v, want %v", err, ErrTxFailed)
+	}
+}
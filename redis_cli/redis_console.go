@@ -17,83 +17,317 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redis"
 	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
 )
 
 type simpleRedisConsole struct {
-	respChan chan *redisgo.Resp
+	addr string
+
+	// commands sent before the prompt is shown, such as AUTH and SELECT.
+	setup [][]interface{}
+
+	// line editor for stdin, everything the console prints goes through it.
+	term consoleTerm
+
+	// subscriptions confirmed by the last SUBSCRIBE family command,
+	// the console is in subscribed mode while it is non-zero.
+	subscribed int32
+
+	// set between a successful MULTI and EXEC or DISCARD.
+	transaction int32
+
+	// set while MONITOR or CLIENT TRACKING output is streamed.
+	streaming int32
+
+	// the console outlives connections, commands go to the current one.
+	mutex     sync.Mutex
+	sender    *contextSender
+	connected bool
+	attached  sync.Once
+	exited    chan struct{}
+	exitOnce  sync.Once
+}
+
+func newRedisConsole(addr string, setup [][]interface{}) *simpleRedisConsole {
+	return &simpleRedisConsole{addr: addr, setup: setup, term: newConsoleTerm(), exited: make(chan struct{})}
 }
 
 func (s *simpleRedisConsole) HandleActive(ctx netty.ActiveContext) {
-	fmt.Println("connected")
+	fmt.Fprintln(s.term, "connected")
+
+	go func() {
+		sender := &contextSender{ctx}
+		if err := s.runSetup(sender); nil != err {
+			ctx.Close(err)
+			return
+		}
+
+		s.mutex.Lock()
+		s.sender, s.addr, s.connected = sender, ctx.Channel().RemoteAddr(), true
+		s.mutex.Unlock()
 
-	go s.attachConsole(ctx)
+		s.attached.Do(func() {
+			go s.attachConsole(s, s.exit)
+		})
+	}()
 	ctx.HandleActive()
 }
 
+// runSetup sends the setup commands, the first failure is returned.
+func (s *simpleRedisConsole) runSetup(sender commandSender) error {
+	for _, command := range s.setup {
+		if err := errorReply(sender.Send(command...).Wait()); nil != err {
+			fmt.Fprintln(s.term, command[0], "failed:", err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *simpleRedisConsole) HandleRead(ctx netty.InboundContext, message netty.Message) {
-	s.respChan <- message.(*redisgo.Resp)
+	// replies that did not answer any request.
+	resp := message.(*redisgo.Resp)
+	if atomic.LoadInt32(&s.streaming) > 0 {
+		line := resp.Data
+		if resp.Kind != redisgo.SimpleKind {
+			line = formatCSV(resp)
+		}
+		fmt.Fprintln(s.term, time.Now().Format("15:04:05.000"), line)
+		return
+	}
+	printResp(s.term, resp)
 }
 
 func (s *simpleRedisConsole) HandleInactive(ctx netty.InactiveContext, ex netty.Exception) {
-	fmt.Println("disconnected", ex)
+	fmt.Fprintln(s.term, "disconnected", ex)
+
+	// subscriptions and transactions do not survive the connection.
+	s.mutex.Lock()
+	s.connected = false
+	s.mutex.Unlock()
+	atomic.StoreInt32(&s.subscribed, 0)
+	atomic.StoreInt32(&s.transaction, 0)
 
 	ctx.HandleInactive(ex)
 }
 
-func (s *simpleRedisConsole) attachConsole(ctx netty.HandlerContext) {
+// printMessage prints messages published while subscribed.
+func (s *simpleRedisConsole) printMessage(msg *redis.Message) {
+	fields := []string{"message", msg.Channel, msg.Payload}
+	if "" != msg.Pattern {
+		fields = []string{"pmessage", msg.Pattern, msg.Channel, msg.Payload}
+	}
 
-	s.respChan = make(chan *redisgo.Resp, 1)
+	resp := &redisgo.Resp{Kind: redisgo.ArrayKind, Array: make([]redisgo.Resp, len(fields))}
+	for i, field := range fields {
+		resp.Array[i] = redisgo.Resp{Kind: redisgo.BlukKind, Data: field}
+	}
+	printResp(s.term, resp)
+}
 
-	fmt.Print(ctx.Channel().RemoteAddr(), ">")
+func (s *simpleRedisConsole) prompt() string {
+	s.mutex.Lock()
+	addr, connected := s.addr, s.connected
+	s.mutex.Unlock()
 
-	stdin := bufio.NewScanner(os.Stdin)
-	for stdin.Scan() {
+	switch {
+	case !connected:
+		return "not connected>"
+	case atomic.LoadInt32(&s.subscribed) > 0:
+		return addr + "(subscribed mode)>"
+	case atomic.LoadInt32(&s.transaction) > 0:
+		return addr + "(TX)>"
+	}
+	return addr + ">"
+}
 
-		if text := strings.TrimSpace(stdin.Text()); len(text) > 0 {
+// Send sends a command over the current connection, while the console is
+// reconnecting it fails on the connection that dropped.
+func (s *simpleRedisConsole) Send(args ...interface{}) *redis.Future {
+	s.mutex.Lock()
+	sender := s.sender
+	s.mutex.Unlock()
+	return sender.Send(args...)
+}
 
-			switch text {
-			case "exit":
-				ctx.Close(fmt.Errorf("user exit"))
-				return
-			case "help":
-				fmt.Println("help information")
-			default:
-				inputs := strings.Split(text, " ")
+// exit leaves the console and closes the current connection.
+func (s *simpleRedisConsole) exit(err error) {
+	s.exitOnce.Do(func() {
+		close(s.exited)
+	})
 
-				// build command.
-				var cmds = make([]redisgo.Value, 0, len(inputs))
-				for _, v := range inputs {
-					cmds = append(cmds, redisgo.BlukString(v))
-				}
+	s.mutex.Lock()
+	sender := s.sender
+	s.mutex.Unlock()
+	sender.ctx.Close(err)
+}
 
-				// send redis command.
-				ctx.Write(cmds)
-
-				// print response
-				resp := <-s.respChan
-
-				switch {
-				case resp.Null:
-					fmt.Println("(empty)")
-				case len(resp.Data) > 0:
-					fmt.Println(resp.Data)
-				case len(resp.Array) > 0:
-					for index, rsp := range resp.Array {
-						fmt.Println(fmt.Sprintf(`%d) "%s"`, index+1, rsp.Data))
-					}
-				}
+// Exited is closed once the user left the console.
+func (s *simpleRedisConsole) Exited() <-chan struct{} {
+	return s.exited
+}
+
+// commandSender sends one command, either through the console pipeline
+// or through a cluster client.
+type commandSender interface {
+	Send(args ...interface{}) *redis.Future
+}
+
+type contextSender struct {
+	ctx netty.HandlerContext
+}
+
+func (c *contextSender) Send(args ...interface{}) *redis.Future {
+	request := redis.NewRequest(redis.Args(args...)...)
+	c.ctx.Write(request)
+	return request.Future
+}
+
+func (s *simpleRedisConsole) attachConsole(sender commandSender, exit func(err error)) {
+
+	for {
+		text, err := s.term.ReadLine(s.prompt())
+		if nil != err {
+			// Ctrl-C, Ctrl-D or end of input.
+			exit(fmt.Errorf("user exit"))
+			return
+		}
+
+		text = strings.TrimSpace(text)
+		if 0 == len(text) {
+			continue
+		}
+
+		inputs, err := redisgo.SplitArgs(text)
+		if nil != err {
+			fmt.Fprintln(s.term, "Invalid argument(s):", err)
+			continue
+		}
+		s.term.AddHistory(text)
+
+		switch strings.ToLower(inputs[0]) {
+		case "exit", "quit":
+			exit(fmt.Errorf("user exit"))
+			return
+		case "help", "?":
+			printHelp(s.term, inputs[1:])
+			continue
+		}
+
+		// streamed output only reaches the console on its own connection.
+		stream := streamingCommand(inputs)
+		if "" != stream && sender != commandSender(s) {
+			fmt.Fprintln(s.term, "(error) ERR", stream, "is not supported in this mode")
+			continue
+		}
+
+		// build command.
+		var cmds = make([]interface{}, 0, len(inputs))
+		for _, v := range inputs {
+			cmds = append(cmds, v)
+		}
+
+		// send redis command.
+		future := sender.Send(cmds...)
+
+		// print response
+		resp, err := future.Wait()
+		if nil != err {
+			fmt.Fprintln(s.term, err)
+			continue
+		}
+
+		// SUBSCRIBE family commands are confirmed once per channel.
+		if replies := future.Replies(); len(replies) > 0 {
+			for i := range replies {
+				printResp(s.term, &replies[i])
+			}
+			if count, err := strconv.Atoi(resp.Array[2].Data); nil == err {
+				atomic.StoreInt32(&s.subscribed, int32(count))
+			}
+			if atomic.LoadInt32(&s.subscribed) > 0 {
+				fmt.Fprintln(s.term, "Reading messages... (UNSUBSCRIBE or PUNSUBSCRIBE to leave subscribed mode)")
 			}
 		} else {
-			fmt.Println("please input redis command")
+			printResp(s.term, resp)
+
+			// EXEC and DISCARD end the transaction even when they fail.
+			switch strings.ToLower(inputs[0]) {
+			case "multi":
+				if nil == errorReply(resp, nil) {
+					atomic.StoreInt32(&s.transaction, 1)
+				}
+			case "exec", "discard":
+				atomic.StoreInt32(&s.transaction, 0)
+			}
+
+			if "" != stream && nil == errorReply(resp, nil) {
+				s.stream(stream)
+			}
 		}
+	}
+}
+
+// streamingCommand returns MONITOR or CLIENT TRACKING for commands whose
+// output is pushed without end, or "" for any other command.
+func streamingCommand(inputs []string) string {
+	switch {
+	case strings.EqualFold(inputs[0], "monitor"):
+		return "MONITOR"
+	case len(inputs) > 2 && strings.EqualFold(inputs[0], "client") &&
+		strings.EqualFold(inputs[1], "tracking") && strings.EqualFold(inputs[2], "on"):
+		return "CLIENT TRACKING"
+	}
+	return ""
+}
 
-		fmt.Print(ctx.Channel().RemoteAddr(), ">")
+// stream prints the output of command with the local time until Ctrl-C,
+// then stops it on the server and returns to the prompt.
+func (s *simpleRedisConsole) stream(command string) {
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	s.mutex.Lock()
+	sender := s.sender
+	s.mutex.Unlock()
+
+	atomic.StoreInt32(&s.streaming, 1)
+	defer atomic.StoreInt32(&s.streaming, 0)
+
+	fmt.Fprintln(s.term, "Streaming", command, "output, press Ctrl-C to stop.")
+	select {
+	case <-interrupt:
+	case <-sender.ctx.Channel().Context().Done():
+		return
+	}
+
+	switch command {
+	case "MONITOR":
+		// RESET leaves monitor mode, it also undoes AUTH and SELECT. Older
+		// servers do not know it, a new connection is set up instead.
+		err := errorReply(sender.Send("RESET").Wait())
+		if nil == err {
+			err = s.runSetup(sender)
+		}
+		if nil != err {
+			sender.ctx.Close(err)
+		}
+	case "CLIENT TRACKING":
+		if err := errorReply(sender.Send("CLIENT", "TRACKING", "OFF").Wait()); nil != err {
+			fmt.Fprintln(s.term, err)
+		}
 	}
 }
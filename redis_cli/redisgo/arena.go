@@ -0,0 +1,137 @@
+package redisgo
+
+import (
+	"sync"
+	"unsafe"
+)
+
+const (
+	// size of the first buffer of an arena.
+	minArenaChunk = 512
+
+	// arenas that grew beyond this are not kept for reuse.
+	maxArenaRetained = 1 << 20
+)
+
+// arena hands out the strings and elements of one reply from a few large
+// buffers. Once a buffer is full a new one is started, the old one stays
+// alive as long as the strings pointing into it.
+type arena struct {
+	data  []byte
+	resps []Resp
+	pairs []RespPair
+}
+
+func (a *arena) bytes(n int) []byte {
+	if cap(a.data)-len(a.data) < n {
+		a.data = make([]byte, 0, max(2*cap(a.data), n, minArenaChunk))
+	}
+	b := a.data[len(a.data) : len(a.data)+n]
+	a.data = a.data[:len(a.data)+n]
+	return b
+}
+
+// str copies b into the arena and returns a string sharing its memory.
+func (a *arena) str(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	p := a.bytes(len(b))
+	copy(p, b)
+	return *(*string)(unsafe.Pointer(&p))
+}
+
+// makeResps returns an empty slice with room for n elements.
+func (a *arena) makeResps(n int) []Resp {
+	if n == 0 {
+		return []Resp{}
+	}
+	if cap(a.resps)-len(a.resps) < n {
+		a.resps = make([]Resp, 0, max(2*cap(a.resps), n, minArenaChunk/8))
+	}
+	s := a.resps[len(a.resps) : len(a.resps)+n : len(a.resps)+n]
+	clear(s)
+	a.resps = a.resps[:len(a.resps)+n]
+	return s[:0]
+}
+
+// makePairs returns an empty slice with room for n pairs.
+func (a *arena) makePairs(n int) []RespPair {
+	if n == 0 {
+		return []RespPair{}
+	}
+	if cap(a.pairs)-len(a.pairs) < n {
+		a.pairs = make([]RespPair, 0, max(2*cap(a.pairs), n, minArenaChunk/16))
+	}
+	s := a.pairs[len(a.pairs) : len(a.pairs)+n : len(a.pairs)+n]
+	clear(s)
+	a.pairs = a.pairs[:len(a.pairs)+n]
+	return s[:0]
+}
+
+func (a *arena) reset() {
+	// drop the references to the strings of the last reply.
+	clear(a.resps)
+	clear(a.pairs)
+
+	if cap(a.data) > maxArenaRetained {
+		a.data = nil
+	}
+	if cap(a.resps)*int(unsafe.Sizeof(Resp{})) > maxArenaRetained {
+		a.resps = nil
+	}
+	if cap(a.pairs)*int(unsafe.Sizeof(RespPair{})) > maxArenaRetained {
+		a.pairs = nil
+	}
+	a.data, a.resps, a.pairs = a.data[:0], a.resps[:0], a.pairs[:0]
+}
+
+// makeResps returns an empty slice with room for n elements, from the
+// arena if there is one. The announced length is only trusted up to
+// maxPrealloc.
+func makeResps(a *arena, n int) []Resp {
+	if a != nil && n <= maxPrealloc {
+		return a.makeResps(n)
+	}
+	return make([]Resp, 0, min(n, maxPrealloc))
+}
+
+func makePairs(a *arena, n int) []RespPair {
+	if a != nil && n <= maxPrealloc {
+		return a.makePairs(n)
+	}
+	return make([]RespPair, 0, min(n, maxPrealloc))
+}
+
+func makeString(a *arena, b []byte) string {
+	if a != nil {
+		return a.str(b)
+	}
+	return string(b)
+}
+
+var replyPool = sync.Pool{
+	New: func() interface{} {
+		return new(Reply)
+	},
+}
+
+// Reply is a reply decoded by Decoder.DecodeReply or
+// StreamDecoder.FeedReplies. Its strings and slices share buffers that are
+// reused once the reply is released, nothing of it may be kept after
+// calling Release.
+type Reply struct {
+	Resp
+	arena arena
+}
+
+func newReply() *Reply {
+	return replyPool.Get().(*Reply)
+}
+
+// Release returns the reply and its buffers to the pool.
+func (r *Reply) Release() {
+	r.Resp = Resp{}
+	r.arena.reset()
+	replyPool.Put(r)
+}
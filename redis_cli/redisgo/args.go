@@ -0,0 +1,121 @@
+package redisgo
+
+import "errors"
+
+// ErrUnbalancedQuotes is returned by SplitArgs for a quoted argument that
+// is not closed, or is not followed by a space.
+var ErrUnbalancedQuotes = errors.New("unbalanced quotes")
+
+// SplitArgs splits a command line into arguments the way redis-cli does.
+// Arguments are separated by whitespace, double quoted arguments support
+// the escapes \n \r \t \b \a \" \\ and \xHH, single quoted arguments only
+// support \'.
+func SplitArgs(line string) ([]string, error) {
+	var args []string
+	for p := 0; ; {
+		// skip blanks.
+		for p < len(line) && isSpace(line[p]) {
+			p++
+		}
+		if p == len(line) {
+			return args, nil
+		}
+
+		var current []byte
+		inq, insq := false, false
+		for done := false; !done; p++ {
+			switch {
+			case inq:
+				if p == len(line) {
+					return nil, ErrUnbalancedQuotes
+				}
+				switch c := line[p]; {
+				case c == '\\' && p+3 < len(line) && line[p+1] == 'x' && isHex(line[p+2]) && isHex(line[p+3]):
+					current = append(current, unhex(line[p+2])<<4|unhex(line[p+3]))
+					p += 3
+				case c == '\\' && p+1 < len(line):
+					p++
+					switch c = line[p]; c {
+					case 'n':
+						c = '\n'
+					case 'r':
+						c = '\r'
+					case 't':
+						c = '\t'
+					case 'b':
+						c = '\b'
+					case 'a':
+						c = '\a'
+					}
+					current = append(current, c)
+				case c == '"':
+					// closing quote must be followed by a space or nothing at all.
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					current = append(current, c)
+				}
+			case insq:
+				if p == len(line) {
+					return nil, ErrUnbalancedQuotes
+				}
+				switch c := line[p]; {
+				case c == '\\' && p+1 < len(line) && line[p+1] == '\'':
+					current = append(current, '\'')
+					p++
+				case c == '\'':
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					current = append(current, c)
+				}
+			default:
+				if p == len(line) {
+					done = true
+					break
+				}
+				switch c := line[p]; {
+				case isSpace(c) || c == 0:
+					done = true
+				case c == '"':
+					inq = true
+				case c == '\'':
+					insq = true
+				default:
+					current = append(current, c)
+				}
+			}
+		}
+		args = append(args, string(current))
+		if p > len(line) {
+			p = len(line)
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	default:
+		return c - 'a' + 10
+	}
+}
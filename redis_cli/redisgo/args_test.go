@@ -0,0 +1,42 @@
+package redisgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "   ", nil, false},
+		{"plain", "SET k v", []string{"SET", "k", "v"}, false},
+		{"blanks", "  SET \t k    v  ", []string{"SET", "k", "v"}, false},
+		{"double-quotes", `SET k "hello world"`, []string{"SET", "k", "hello world"}, false},
+		{"single-quotes", `SET k 'hello world'`, []string{"SET", "k", "hello world"}, false},
+		{"empty-quotes", `SET k ""`, []string{"SET", "k", ""}, false},
+		{"escapes", `"a\nb\r\t\"\\c"`, []string{"a\nb\r\t\"\\c"}, false},
+		{"hex", `"\x00\xff\x4A"`, []string{"\x00\xff\x4a"}, false},
+		{"bad-hex", `"\xZZ"`, []string{"xZZ"}, false},
+		{"single-escape", `'it\'s \n'`, []string{`it's \n`}, false},
+		{"quote-inside", `foo"bar baz"`, []string{"foobar baz"}, false},
+		{"unbalanced-double", `SET k "hello`, nil, true},
+		{"unbalanced-single", `SET k 'hello`, nil, true},
+		{"trailing-backslash", `"abc\`, nil, true},
+		{"closing-quote", `"foo"bar`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package redisgo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unsafe"
+)
+
+// ProtocolError is a malformed command. Servers reply with "-ERR" followed
+// by it and close the connection, as redis does.
+type ProtocolError string
+
+func (e ProtocolError) Error() string {
+	return "Protocol error: " + string(e)
+}
+
+// CommandDecoder reads the commands sent by clients, either multibulk
+// arrays of bulk strings or inline commands as typed in telnet, which are
+// split like SplitArgs does.
+type CommandDecoder struct {
+	r      *bufio.Reader
+	limits limits
+	read   int // bytes of the current command read so far
+}
+
+// NewCommandDecoder returns a decoder reading commands from r, maxLineSize
+// also bounds the length of inline commands. The array length limit
+// applies to the number of arguments.
+func NewCommandDecoder(r io.Reader, maxLineSize int, options ...DecoderOption) *CommandDecoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(r, maxLineSize)
+	}
+	return &CommandDecoder{
+		r:      br,
+		limits: newLimits(options),
+	}
+}
+
+// Decode returns the arguments of the next command, empty commands are
+// skipped.
+func (d *CommandDecoder) Decode() ([][]byte, error) {
+	for {
+		d.read = 0
+		c, err := d.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+
+		var args [][]byte
+		if c[0] == ArrayKind {
+			args, err = d.readMultibulk()
+		} else {
+			args, err = d.readInline()
+		}
+		if err != nil || len(args) > 0 {
+			return args, err
+		}
+	}
+}
+
+func (d *CommandDecoder) readLine(tooBig string) ([]byte, error) {
+	ln, err := d.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, ProtocolError(tooBig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.read += len(ln)
+	if err = d.limits.checkReplySize(d.read); err != nil {
+		return nil, err
+	}
+	return ln, nil
+}
+
+func (d *CommandDecoder) readInline() ([][]byte, error) {
+	ln, err := d.readLine("too big inline request")
+	if err != nil {
+		return nil, err
+	}
+
+	// telnet sends CRLF, netcat only LF.
+	ln = bytes.TrimSuffix(ln[:len(ln)-1], []byte{'\r'})
+	words, err := SplitArgs(*(*string)(unsafe.Pointer(&ln)))
+	if err != nil {
+		return nil, ProtocolError("unbalanced quotes in request")
+	}
+	if err = d.limits.checkArrayLen(len(words)); err != nil {
+		return nil, err
+	}
+
+	args := make([][]byte, len(words))
+	for i, word := range words {
+		args[i] = []byte(word)
+	}
+	return args, nil
+}
+
+// readHeader reads a "*n" or "$n" line and returns n.
+func (d *CommandDecoder) readHeader(kind RespKind, tooBig, invalid string) (int, error) {
+	ln, err := d.readLine(tooBig)
+	if err != nil {
+		return 0, err
+	}
+	if ln[0] != byte(kind) {
+		return 0, ProtocolError(fmt.Sprintf("expected '%c', got '%c'", kind, ln[0]))
+	}
+	if len(ln) < 3 || ln[len(ln)-2] != '\r' {
+		return 0, ProtocolError(invalid)
+	}
+	ln = ln[1 : len(ln)-2]
+	n, err := strconv.Atoi(*(*string)(unsafe.Pointer(&ln)))
+	if err != nil {
+		return 0, ProtocolError(invalid)
+	}
+	return n, nil
+}
+
+func (d *CommandDecoder) readMultibulk() ([][]byte, error) {
+	n, err := d.readHeader(ArrayKind, "too big mbulk count string", "invalid multibulk length")
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	if err = d.limits.checkArrayLen(n); err != nil {
+		return nil, err
+	}
+
+	args := make([][]byte, 0, min(n, maxPrealloc))
+	for i := 0; i < n; i++ {
+		size, err := d.readHeader(BlukKind, "too big bulk count string", "invalid bulk length")
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, ProtocolError("invalid bulk length")
+		}
+		if err = d.limits.checkBulkSize(size); err != nil {
+			return nil, err
+		}
+		d.read += size + 2
+		if err = d.limits.checkReplySize(d.read); err != nil {
+			return nil, err
+		}
+
+		arg := make([]byte, size+2)
+		if _, err = io.ReadFull(d.r, arg); err != nil {
+			return nil, err
+		}
+		if arg[size] != '\r' || arg[size+1] != '\n' {
+			return nil, ProtocolError("expect terminated with CRLF")
+		}
+		args = append(args, arg[:size])
+	}
+	return args, nil
+}
@@ -0,0 +1,78 @@
+package redisgo
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCommandDecoder_Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr error
+	}{
+		{"multibulk", "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$7\r\nfoo\nbar\r\n", []string{"SET|a|foo\nbar"}, io.EOF},
+		{"inline", "PING\r\n", []string{"PING"}, io.EOF},
+		{"inline-lf", "SET a b\nGET a\n", []string{"SET|a|b", "GET|a"}, io.EOF},
+		{"inline-quotes", "SET k \"hello world\" 'x'\r\n", []string{"SET|k|hello world|x"}, io.EOF},
+		{"inline-escapes", "SET k \"\\x00\\n\"\r\n", []string{"SET|k|\x00\n"}, io.EOF},
+		{"mixed", "PING\r\n*1\r\n$4\r\nPING\r\n  ECHO   hi  \r\n", []string{"PING", "PING", "ECHO|hi"}, io.EOF},
+		{"empty", "\r\n\n*0\r\n*-1\r\nPING\r\n", []string{"PING"}, io.EOF},
+		{"unbalanced", "SET k \"oops\r\n", nil, ProtocolError("unbalanced quotes in request")},
+		{"not-bulk", "*1\r\n:1\r\n", nil, ProtocolError("expected '$', got ':'")},
+		{"bad-count", "*x\r\n", nil, ProtocolError("invalid multibulk length")},
+		{"bad-length", "*1\r\n$-2\r\n", nil, ProtocolError("invalid bulk length")},
+		{"bad-crlf", "*1\r\n$4\r\nPINGXX", nil, ProtocolError("expect terminated with CRLF")},
+		{"too-big", "PING " + strings.Repeat("x", 64), nil, ProtocolError("too big inline request")},
+		{"truncated", "*2\r\n$4\r\nECHO\r\n", nil, io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewCommandDecoder(strings.NewReader(tt.input), 32)
+			var got []string
+			var err error
+			for {
+				var args [][]byte
+				if args, err = d.Decode(); err != nil {
+					break
+				}
+				var words []string
+				for _, arg := range args {
+					words = append(words, string(arg))
+				}
+				got = append(got, strings.Join(words, "|"))
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CommandDecoder.Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("CommandDecoder.Decode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandDecoder_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		option  DecoderOption
+		wantErr error
+	}{
+		{"args", "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n", WithMaxArrayLen(2), ErrMaxArrayLen},
+		{"inline-args", "a b c\r\n", WithMaxArrayLen(2), ErrMaxArrayLen},
+		{"bulk", "*1\r\n$5\r\nhello\r\n", WithMaxBulkSize(4), ErrMaxBulkSize},
+		{"size", "*2\r\n$3\r\nGET\r\n$8\r\nsomekey1\r\n", WithMaxReplySize(24), ErrMaxReplySize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCommandDecoder(strings.NewReader(tt.input), 1024, tt.option).Decode()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CommandDecoder.Decode() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,7 +13,17 @@ const (
 )
 
 type Decoder struct {
-	r *bufio.Reader
+	r      *bufio.Reader
+	limits limits
+	depth  int // aggregates the value being decoded is nested in
+	read   int // bytes of the current reply read so far
+	arena  *arena
+}
+
+// consume counts n more bytes of the current reply.
+func (d *Decoder) consume(n int) error {
+	d.read += n
+	return d.limits.checkReplySize(d.read)
 }
 
 func (d *Decoder) readLine() ([]byte, error) {
@@ -21,93 +31,261 @@ func (d *Decoder) readLine() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = d.consume(len(ln)); err != nil {
+		return nil, err
+	}
 	if len(ln) < 2 || ln[len(ln)-2] != '\r' {
 		return nil, fmt.Errorf("expect terminated with CRLF")
 	}
 	return ln[:len(ln)-2], nil
 }
 
+func (d *Decoder) readLength() (int, error) {
+	ln, err := d.readLine()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(*(*string)(unsafe.Pointer(&ln)))
+}
+
+func (d *Decoder) readBluk(n int) (string, error) {
+	if err := d.consume(n + 2); err != nil {
+		return "", err
+	}
+	var data []byte
+	if d.arena != nil {
+		data = d.arena.bytes(n + 2)
+	} else {
+		data = make([]byte, n+2)
+	}
+	_, err := io.ReadFull(d.r, data)
+	if err != nil {
+		return "", err
+	}
+	if data[len(data)-2] != '\r' || data[len(data)-1] != '\n' {
+		return "", fmt.Errorf("expect terminated with CRLF")
+	}
+	data = data[:len(data)-2]
+	if d.arena != nil {
+		return *(*string)(unsafe.Pointer(&data)), nil
+	}
+	return string(data), nil
+}
+
+// readPairs reads the pairs of a map or attribute entered with enter.
+func (d *Decoder) readPairs(n int) ([]RespPair, error) {
+	pairs := makePairs(d.arena, n)
+	for i := 0; i < n; i++ {
+		pairs = append(pairs, RespPair{})
+		if err := d.decode(&pairs[i].Key); err != nil {
+			return nil, err
+		}
+		if err := d.decode(&pairs[i].Value); err != nil {
+			return nil, err
+		}
+	}
+	return pairs, nil
+}
+
+// enter starts an aggregate of n elements or pairs.
+func (d *Decoder) enter(n int) error {
+	if err := d.limits.checkArrayLen(n); err != nil {
+		return err
+	}
+	d.depth++
+	if err := d.limits.checkDepth(d.depth); err != nil {
+		return err
+	}
+	// decode recurses, it is bounded even with the limit disabled.
+	return exceeds(ErrMaxDepth, maxNesting, d.depth)
+}
+
+func (d *Decoder) leave() {
+	d.depth--
+}
+
+// Decode reads the next reply into r.
 func (d *Decoder) Decode(r *Resp) error {
+	d.depth, d.read = 0, 0
+	return d.decode(r)
+}
+
+func (d *Decoder) decode(r *Resp) error {
 	ch, err := d.r.ReadByte()
 	if err != nil {
 		return err
 	}
+	if err = d.consume(1); err != nil {
+		return err
+	}
 	switch RespKind(ch) {
-	case SimpleKind, ErrorKind, IntegerKind:
+	case SimpleKind, ErrorKind, IntegerKind, DoubleKind, BigNumberKind:
 		ln, err := d.readLine()
 		if err != nil {
 			return err
 		}
 		*r = Resp{
 			Kind: RespKind(ch),
-			Data: string(ln),
+			Data: makeString(d.arena, ln),
 		}
-	case BlukKind:
+	case BooleanKind:
 		ln, err := d.readLine()
 		if err != nil {
 			return err
 		}
-		n, err := strconv.Atoi(*(*string)(unsafe.Pointer(&ln)))
+		if len(ln) != 1 || (ln[0] != 't' && ln[0] != 'f') {
+			return fmt.Errorf("invalid boolean: %q", ln)
+		}
+		*r = Resp{
+			Kind: RespKind(ch),
+			Data: makeString(d.arena, ln),
+		}
+	case NullKind:
+		ln, err := d.readLine()
 		if err != nil {
 			return err
 		}
-		if n < -1 || n > maxBlukSize {
+		if len(ln) != 0 {
+			return fmt.Errorf("invalid null: %q", ln)
+		}
+		*r = Resp{
+			Kind: RespKind(ch),
+			Null: true,
+		}
+	case BlukKind, BlobErrorKind, VerbatimKind:
+		n, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		if n < -1 {
 			return fmt.Errorf("invalid bluk length: %d", n)
 		}
+		if err = d.limits.checkBulkSize(n); err != nil {
+			return err
+		}
 		if n == -1 {
 			*r = Resp{
 				Kind: RespKind(ch),
 				Null: true,
 			}
 		} else {
-			data := make([]byte, n+2)
-			_, err := io.ReadFull(d.r, data)
+			data, err := d.readBluk(n)
 			if err != nil {
 				return err
 			}
-			if data[len(data)-2] != '\r' || data[len(data)-1] != '\n' {
-				return fmt.Errorf("expect terminated with CRLF")
+			if RespKind(ch) == VerbatimKind && (len(data) < 4 || data[3] != ':') {
+				return fmt.Errorf("invalid verbatim string: %q", data)
 			}
 			*r = Resp{
 				Kind: RespKind(ch),
-				Data: string(data[:len(data)-2]),
+				Data: data,
 			}
 		}
-	case ArrayKind:
-		ln, err := d.readLine()
-		if err != nil {
-			return err
-		}
-		n, err := strconv.Atoi(*(*string)(unsafe.Pointer(&ln)))
+	case ArrayKind, SetKind, PushKind:
+		n, err := d.readLength()
 		if err != nil {
 			return err
 		}
-		if n < 0 {
+		if n < -1 {
 			return fmt.Errorf("invalid array length: %d", n)
 		}
-		array := make([]Resp, n)
+		if n == -1 {
+			*r = Resp{
+				Kind: RespKind(ch),
+				Null: true,
+			}
+			return nil
+		}
+		if err = d.enter(n); err != nil {
+			return err
+		}
+		array := makeResps(d.arena, n)
 		for i := 0; i < n; i++ {
-			err = d.Decode(&array[i])
+			array = append(array, Resp{})
+			err = d.decode(&array[i])
 			if err != nil {
 				return err
 			}
 		}
+		d.leave()
 		*r = Resp{
 			Kind:  RespKind(ch),
 			Array: array,
 		}
+	case MapKind:
+		n, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid map length: %d", n)
+		}
+		if err = d.enter(n); err != nil {
+			return err
+		}
+		pairs, err := d.readPairs(n)
+		if err != nil {
+			return err
+		}
+		d.leave()
+		*r = Resp{
+			Kind: RespKind(ch),
+			Map:  pairs,
+		}
+	case AttributeKind:
+		n, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid attribute length: %d", n)
+		}
+		if err = d.enter(n); err != nil {
+			return err
+		}
+		attrs, err := d.readPairs(n)
+		if err != nil {
+			return err
+		}
+		// attributes describe the reply that follows them, it is counted
+		// at the depth of the attribute, so chained attributes nest.
+		if err = d.decode(r); err != nil {
+			return err
+		}
+		d.leave()
+		if len(attrs) > 0 {
+			r.Attrs = append(attrs, r.Attrs...)
+		}
 	default:
 		return fmt.Errorf("unrecognized kind: %c", ch)
 	}
 	return nil
 }
 
-func NewDecoder(r io.Reader, maxLineSize int) *Decoder {
+// DecodeReply reads the next reply like Decode, but into pooled buffers
+// that are reused once the reply is released. Most replies are decoded
+// without any allocation.
+func (d *Decoder) DecodeReply() (*Reply, error) {
+	reply := newReply()
+	d.arena = &reply.arena
+	err := d.Decode(&reply.Resp)
+	d.arena = nil
+	if err != nil {
+		reply.Release()
+		return nil, err
+	}
+	return reply, nil
+}
+
+// NewDecoder returns a decoder reading replies from r, options set the
+// limits of the replies it accepts.
+func NewDecoder(r io.Reader, maxLineSize int, options ...DecoderOption) *Decoder {
 	br, ok := r.(*bufio.Reader)
 	if !ok {
 		br = bufio.NewReaderSize(r, maxLineSize)
 	}
 	return &Decoder{
-		r: br,
+		r:      br,
+		limits: newLimits(options),
 	}
 }
@@ -1,6 +1,9 @@
 package redisgo
 
 import (
+	"bytes"
+	"errors"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -22,6 +25,20 @@ func TestDecoder_Decode(t *testing.T) {
 		{"bluk", "$7\r\nfoo\nbar\r\n", args{&Resp{}}, false},
 		{"array", "*3\r\n$3\r\nfoo\r\n$-1\r\n$3\r\nbar\r\n", args{&Resp{}}, false},
 		{"array-in-array", "*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n", args{&Resp{}}, false},
+		{"null-array", "*-1\r\n", args{&Resp{}}, false},
+		{"null", "_\r\n", args{&Resp{}}, false},
+		{"double", ",3.14\r\n", args{&Resp{}}, false},
+		{"boolean", "#t\r\n", args{&Resp{}}, false},
+		{"boolean-invalid", "#x\r\n", args{&Resp{}}, true},
+		{"big-number", "(3492890328409238509324850943850943825024385\r\n", args{&Resp{}}, false},
+		{"blob-error", "!21\r\nSYNTAX invalid syntax\r\n", args{&Resp{}}, false},
+		{"verbatim", "=15\r\ntxt:Some string\r\n", args{&Resp{}}, false},
+		{"verbatim-invalid", "=4\r\ntext\r\n", args{&Resp{}}, true},
+		{"map", "%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n", args{&Resp{}}, false},
+		{"set", "~2\r\n+orange\r\n+apple\r\n", args{&Resp{}}, false},
+		{"attribute", "|1\r\n+ttl\r\n:3600\r\n$3\r\nfoo\r\n", args{&Resp{}}, false},
+		{"push", ">3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n", args{&Resp{}}, false},
+		{"unknown", "?1\r\n", args{&Resp{}}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -34,3 +51,220 @@ func TestDecoder_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecoder_RoundTrip(t *testing.T) {
+	tests := []string{
+		"+OK\r\n",
+		"$-1\r\n",
+		"*-1\r\n",
+		"*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n",
+		"_\r\n",
+		",-inf\r\n",
+		"#f\r\n",
+		"(-12345678901234567890\r\n",
+		"!10\r\nERR oops\r\n\r\n",
+		"=15\r\ntxt:Some string\r\n",
+		"%2\r\n+first\r\n:1\r\n$6\r\nsecond\r\n~1\r\n#t\r\n",
+		"|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.1923\r\n*1\r\n|1\r\n+ttl\r\n:10\r\n:2039123\r\n",
+		">2\r\n$10\r\ninvalidate\r\n*1\r\n$3\r\nfoo\r\n",
+	}
+	for _, input := range tests {
+		t.Run(strconv.Quote(input), func(t *testing.T) {
+			r := &Resp{}
+			if err := NewDecoder(strings.NewReader(input), 1024).Decode(r); err != nil {
+				t.Fatalf("Decoder.Decode() error = %v", err)
+			}
+			if got := r.String(); got != input {
+				t.Errorf("Decoder.Decode() round-trip = %q, want %q", got, input)
+			}
+		})
+	}
+}
+
+func TestDecoder_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		option  DecoderOption
+		wantErr error
+	}{
+		{"depth", "*1\r\n*1\r\n*1\r\n:1\r\n", WithMaxDepth(2), ErrMaxDepth},
+		{"depth-ok", "*1\r\n*1\r\n:1\r\n", WithMaxDepth(2), nil},
+		{"depth-attribute", "*1\r\n|1\r\n+a\r\n*1\r\n:1\r\n:2\r\n", WithMaxDepth(2), ErrMaxDepth},
+		{"attribute-chain", "|1\r\n+a\r\n+b\r\n|1\r\n+a\r\n+b\r\n|1\r\n+a\r\n+b\r\n:1\r\n", WithMaxDepth(2), ErrMaxDepth},
+		{"attribute-ok", "|1\r\n+a\r\n+b\r\n*1\r\n:1\r\n", WithMaxDepth(2), nil},
+		{"array-len", "*3\r\n:1\r\n:2\r\n:3\r\n", WithMaxArrayLen(2), ErrMaxArrayLen},
+		{"map-len", "%2\r\n:1\r\n:2\r\n:3\r\n:4\r\n", WithMaxArrayLen(2), nil},
+		{"huge-array", "*2147483647\r\n", WithMaxArrayLen(1024), ErrMaxArrayLen},
+		{"bulk-size", "$5\r\nhello\r\n", WithMaxBulkSize(4), ErrMaxBulkSize},
+		{"huge-bulk", "$536870913\r\n", nil, ErrMaxBulkSize},
+		{"reply-size", "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n", WithMaxReplySize(20), ErrMaxReplySize},
+		{"reply-size-ok", "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n", WithMaxReplySize(22), nil},
+		{"disabled", "*1\r\n*1\r\n*1\r\n:1\r\n", WithMaxDepth(0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []DecoderOption
+			if tt.option != nil {
+				options = append(options, tt.option)
+			}
+
+			err := NewDecoder(strings.NewReader(tt.input), 1024, options...).Decode(&Resp{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decoder.Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			var limitErr *LimitError
+			if tt.wantErr != nil && !errors.As(err, &limitErr) {
+				t.Errorf("Decoder.Decode() error = %T, want *LimitError", err)
+			}
+
+			_, err = NewStreamDecoder(1024, options...).Feed([]byte(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("StreamDecoder.Feed() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecoder_MaxNesting(t *testing.T) {
+	input := strings.Repeat("*1\r\n", maxNesting+1) + ":1\r\n"
+	err := NewDecoder(strings.NewReader(input), 1024, WithMaxDepth(0)).Decode(&Resp{})
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("Decoder.Decode() error = %v, want %v", err, ErrMaxDepth)
+	}
+}
+
+func FuzzDecoder(f *testing.F) {
+	for _, seed := range []string{
+		"+OK\r\n",
+		"-ERR unknown command\r\n",
+		":1000\r\n",
+		"$7\r\nfoo\nbar\r\n",
+		"$-1\r\n",
+		"*3\r\n$3\r\nfoo\r\n$-1\r\n$3\r\nbar\r\n",
+		"*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n",
+		"*-1\r\n",
+		"_\r\n",
+		",3.14\r\n",
+		"#t\r\n",
+		"(3492890328409238509324850943850943825024385\r\n",
+		"!21\r\nSYNTAX invalid syntax\r\n",
+		"=15\r\ntxt:Some string\r\n",
+		"%2\r\n+first\r\n:1\r\n+second\r\n~1\r\n#f\r\n",
+		"|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.1923\r\n*1\r\n|1\r\n+ttl\r\n:10\r\n:2039123\r\n",
+		">3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	options := []DecoderOption{WithMaxDepth(16), WithMaxArrayLen(1024), WithMaxBulkSize(1 << 16), WithMaxReplySize(1 << 20)}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		r := &Resp{}
+		if err := NewDecoder(bytes.NewReader(input), 1024, options...).Decode(r); err != nil {
+			return
+		}
+
+		// a decoded reply encodes to bytes that decode to the same reply.
+		var encoded bytes.Buffer
+		if err := EncodeResp(&encoded, r); err != nil {
+			t.Fatalf("EncodeResp(%q) error = %v", input, err)
+		}
+		again := &Resp{}
+		if err := NewDecoder(bytes.NewReader(encoded.Bytes()), 1024, options...).Decode(again); err != nil {
+			t.Fatalf("Decoder.Decode(%q) error = %v, encoded from %q", encoded.Bytes(), err, input)
+		}
+		if !reflect.DeepEqual(r, again) {
+			t.Fatalf("round-trip of %q = %q, want %q", input, again.String(), r.String())
+		}
+
+		// pooled replies are the same.
+		reply, err := NewDecoder(bytes.NewReader(input), 1024, options...).DecodeReply()
+		if err != nil || !reflect.DeepEqual(r, &reply.Resp) {
+			t.Fatalf("Decoder.DecodeReply(%q) = %v, %v, want %q", input, reply, err, r.String())
+		}
+		reply.Release()
+
+		// the stream decoder agrees, however the input is split.
+		for _, size := range []int{len(input), 7, 1} {
+			d := NewStreamDecoder(len(input)+1, options...)
+			var got []Resp
+			for i := 0; i < len(input) && len(got) == 0; i += size {
+				rs, err := d.Feed(input[i:min(i+size, len(input))])
+				got = append(got, rs...)
+				if err != nil {
+					break
+				}
+			}
+			if len(got) == 0 || !reflect.DeepEqual(r, &got[0]) {
+				t.Fatalf("StreamDecoder.Feed(%q) in chunks of %d = %v, want %q", input, size, got, r.String())
+			}
+
+			d = NewStreamDecoder(len(input)+1, options...)
+			var replies []*Reply
+			for i := 0; i < len(input) && len(replies) == 0; i += size {
+				rs, err := d.FeedReplies(input[i:min(i+size, len(input))])
+				replies = append(replies, rs...)
+				if err != nil {
+					break
+				}
+			}
+			if len(replies) == 0 || !reflect.DeepEqual(r, &replies[0].Resp) {
+				t.Fatalf("StreamDecoder.FeedReplies(%q) in chunks of %d = %v, want %q", input, size, replies, r.String())
+			}
+			for _, reply := range replies {
+				reply.Release()
+			}
+		}
+	})
+}
+
+// benchReply is an HGETALL-like map followed by an array of values.
+var benchReply = func() []byte {
+	var b bytes.Buffer
+	b.WriteString("*2\r\n%20\r\n")
+	for i := 0; i < 20; i++ {
+		b.WriteString("$7\r\nfield" + strconv.Itoa(10+i) + "\r\n:" + strconv.Itoa(i) + "\r\n")
+	}
+	b.WriteString("*100\r\n")
+	for i := 0; i < 100; i++ {
+		b.WriteString("$32\r\n" + strings.Repeat(strconv.Itoa(i%10), 32) + "\r\n")
+	}
+	return b.Bytes()
+}()
+
+// loopReader returns the same bytes over and over.
+type loopReader struct {
+	data []byte
+	off  int
+}
+
+func (l *loopReader) Read(p []byte) (int, error) {
+	n := copy(p, l.data[l.off:])
+	l.off = (l.off + n) % len(l.data)
+	return n, nil
+}
+
+func BenchmarkDecoder_Decode(b *testing.B) {
+	d := NewDecoder(&loopReader{data: benchReply}, 4096)
+	b.SetBytes(int64(len(benchReply)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var r Resp
+		if err := d.Decode(&r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder_DecodeReply(b *testing.B) {
+	d := NewDecoder(&loopReader{data: benchReply}, 4096)
+	b.SetBytes(int64(len(benchReply)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reply, err := d.DecodeReply()
+		if err != nil {
+			b.Fatal(err)
+		}
+		reply.Release()
+	}
+}
@@ -18,6 +18,7 @@ var (
 	simplechar = []byte{'+'}
 	errorchar  = []byte{'-'}
 	nullbluk   = []byte("$-1\r\n")
+	null3      = []byte("_\r\n")
 )
 
 type kind uint
@@ -105,6 +106,33 @@ func Uint64(v uint64) Value {
 	return Value{kind: uint64Kind, u64: uint64(v)}
 }
 
+// String returns the textual form of the value.
+func (v Value) String() string {
+	switch v.kind {
+	case nullKind:
+		return ""
+	case simpleKind, errorKind, blukKind:
+		return v.s
+	case intKind, int8Kind, int16Kind, int32Kind, int64Kind:
+		var buf [32]byte
+		t := buf[:0]
+		switch v.kind {
+		case intKind:
+			t = strconv.AppendInt(t, int64(int(v.u64)), 10)
+		case int8Kind:
+			t = strconv.AppendInt(t, int64(int8(v.u64)), 10)
+		case int16Kind:
+			t = strconv.AppendInt(t, int64(int16(v.u64)), 10)
+		case int32Kind:
+			t = strconv.AppendInt(t, int64(int32(v.u64)), 10)
+		default:
+			t = strconv.AppendInt(t, int64(v.u64), 10)
+		}
+		return string(t)
+	}
+	return strconv.FormatUint(v.u64, 10)
+}
+
 type slice struct {
 	data uintptr
 	n1   int
@@ -221,11 +249,42 @@ func EncodeMulti(w io.Writer, vals ...Value) error {
 	return nil
 }
 
+func appendHeader(b []byte, kind RespKind, n int) []byte {
+	b = append(b, byte(kind))
+	b = strconv.AppendInt(b, int64(n), 10)
+	return append(b, '\r', '\n')
+}
+
+func encodePairs(w io.Writer, kind RespKind, pairs []RespPair) (err error) {
+	var buf [32]byte
+	_, err = w.Write(appendHeader(buf[:0], kind, len(pairs)))
+	if err != nil {
+		return
+	}
+	for i := range pairs {
+		err = EncodeResp(w, &pairs[i].Key)
+		if err != nil {
+			return
+		}
+		err = EncodeResp(w, &pairs[i].Value)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 func EncodeResp(w io.Writer, r *Resp) (err error) {
+	if len(r.Attrs) > 0 {
+		err = encodePairs(w, AttributeKind, r.Attrs)
+		if err != nil {
+			return
+		}
+	}
 	var buf [32]byte
 	b := crlf
 	switch r.Kind {
-	case SimpleKind, ErrorKind, IntegerKind:
+	case SimpleKind, ErrorKind, IntegerKind, DoubleKind, BigNumberKind, BooleanKind:
 		if 3+len(r.Data) <= 32 {
 			t := append(buf[:0], byte(r.Kind))
 			t = append(t, r.Data...)
@@ -240,14 +299,13 @@ func EncodeResp(w io.Writer, r *Resp) (err error) {
 				return
 			}
 		}
-	case BlukKind:
+	case NullKind:
+		b = null3
+	case BlukKind, BlobErrorKind, VerbatimKind:
 		if r.Null {
-			b = nullbluk
+			b = appendHeader(buf[:0], r.Kind, -1)
 		} else {
-			t := append(buf[:0], '$')
-			t = strconv.AppendInt(t, int64(len(r.Data)), 10)
-			t = append(t, '\r', '\n')
-			_, err = w.Write(t)
+			_, err = w.Write(appendHeader(buf[:0], r.Kind, len(r.Data)))
 			if err != nil {
 				return
 			}
@@ -256,11 +314,12 @@ func EncodeResp(w io.Writer, r *Resp) (err error) {
 				return
 			}
 		}
-	case ArrayKind:
-		t := append(buf[:0], '*')
-		t = strconv.AppendInt(t, int64(len(r.Array)), 10)
-		t = append(t, '\r', '\n')
-		_, err = w.Write(t)
+	case ArrayKind, SetKind, PushKind:
+		if r.Null {
+			b = appendHeader(buf[:0], r.Kind, -1)
+			break
+		}
+		_, err = w.Write(appendHeader(buf[:0], r.Kind, len(r.Array)))
 		if err != nil {
 			return
 		}
@@ -271,6 +330,8 @@ func EncodeResp(w io.Writer, r *Resp) (err error) {
 			}
 		}
 		return
+	case MapKind:
+		return encodePairs(w, MapKind, r.Map)
 	default:
 		return fmt.Errorf("unrecognized kind: %c", r.Kind)
 	}
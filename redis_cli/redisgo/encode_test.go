@@ -106,6 +106,24 @@ func TestEncodeResp(t *testing.T) {
 				},
 			},
 		}}, "*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n", false},
+		{"null", args{&Resp{Kind: NullKind, Null: true}}, "_\r\n", false},
+		{"null-array", args{&Resp{Kind: ArrayKind, Null: true}}, "*-1\r\n", false},
+		{"map", args{&Resp{
+			Kind: MapKind,
+			Map: []RespPair{
+				{Key: Resp{Kind: SimpleKind, Data: "first"}, Value: Resp{Kind: DoubleKind, Data: "1.5"}},
+				{Key: Resp{Kind: SimpleKind, Data: "second"}, Value: Resp{Kind: BooleanKind, Data: "t"}},
+			},
+		}}, "%2\r\n+first\r\n,1.5\r\n+second\r\n#t\r\n", false},
+		{"attribute", args{&Resp{
+			Kind:  SetKind,
+			Array: []Resp{{Kind: BlukKind, Data: "a"}},
+			Attrs: []RespPair{
+				{Key: Resp{Kind: SimpleKind, Data: "ttl"}, Value: Resp{Kind: IntegerKind, Data: "10"}},
+			},
+		}}, "|1\r\n+ttl\r\n:10\r\n~1\r\n$1\r\na\r\n", false},
+		{"verbatim", args{&Resp{Kind: VerbatimKind, Data: "txt:hi"}}, "=6\r\ntxt:hi\r\n", false},
+		{"unknown", args{&Resp{Kind: '?'}}, "", true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -120,3 +138,24 @@ func TestEncodeResp(t *testing.T) {
 		})
 	}
 }
+
+func TestValue_String(t *testing.T) {
+	tests := []struct {
+		name string
+		v    Value
+		want string
+	}{
+		{"null", Null(), ""},
+		{"bluk", BlukString("GET"), "GET"},
+		{"int8", Int8(-8), "-8"},
+		{"int64", Int64(-1 << 40), "-1099511627776"},
+		{"uint64", Uint64(1 << 63), "9223372036854775808"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.String(); got != tt.want {
+				t.Errorf("Value.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
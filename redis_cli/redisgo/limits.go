@@ -0,0 +1,121 @@
+package redisgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultMaxDepth is the nesting depth a decoder accepts by default.
+	DefaultMaxDepth = 128
+
+	// DefaultMaxBulkSize is the bulk string size a decoder accepts by
+	// default, the proto-max-bulk-len of redis.
+	DefaultMaxBulkSize = maxBlukSize
+
+	// aggregates announcing more elements than this grow as the elements
+	// arrive, the announced length is not trusted for the allocation.
+	maxPrealloc = 1024
+
+	// Decoder never nests deeper than this, whatever the limit, as it
+	// recurses for every level.
+	maxNesting = 4096
+)
+
+var (
+	ErrMaxDepth     = errors.New("nesting too deep")
+	ErrMaxArrayLen  = errors.New("aggregate too long")
+	ErrMaxBulkSize  = errors.New("bulk string too large")
+	ErrMaxReplySize = errors.New("reply too large")
+)
+
+// LimitError is returned by the decoders when a reply exceeds one of the
+// limits set by DecoderOption.
+type LimitError struct {
+	Err   error // ErrMaxDepth, ErrMaxArrayLen, ErrMaxBulkSize or ErrMaxReplySize
+	Limit int
+	Value int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %d exceeds limit %d", e.Err, e.Value, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// DecoderOption sets a limit of Decoder or StreamDecoder, a limit of zero
+// or less disables it.
+type DecoderOption func(l *limits)
+
+// WithMaxDepth bounds how deep aggregates may be nested, a top level array
+// is at depth 1. An attribute nests the value it describes one level
+// deeper.
+func WithMaxDepth(n int) DecoderOption {
+	return func(l *limits) {
+		l.maxDepth = n
+	}
+}
+
+// WithMaxArrayLen bounds the number of elements an array, set or push may
+// announce, or the number of pairs of a map or attribute.
+func WithMaxArrayLen(n int) DecoderOption {
+	return func(l *limits) {
+		l.maxArrayLen = n
+	}
+}
+
+// WithMaxBulkSize bounds the length of bulk strings, blob errors and
+// verbatim strings.
+func WithMaxBulkSize(n int) DecoderOption {
+	return func(l *limits) {
+		l.maxBulkSize = n
+	}
+}
+
+// WithMaxReplySize bounds how many bytes a reply may take on the wire,
+// counting every nested element.
+func WithMaxReplySize(n int) DecoderOption {
+	return func(l *limits) {
+		l.maxReplySize = n
+	}
+}
+
+type limits struct {
+	maxDepth     int
+	maxArrayLen  int
+	maxBulkSize  int
+	maxReplySize int
+}
+
+func newLimits(options []DecoderOption) limits {
+	l := limits{maxDepth: DefaultMaxDepth, maxBulkSize: DefaultMaxBulkSize}
+	for _, option := range options {
+		option(&l)
+	}
+	return l
+}
+
+func exceeds(err error, limit, value int) error {
+	if limit > 0 && value > limit {
+		return &LimitError{Err: err, Limit: limit, Value: value}
+	}
+	return nil
+}
+
+func (l *limits) checkDepth(depth int) error {
+	return exceeds(ErrMaxDepth, l.maxDepth, depth)
+}
+
+func (l *limits) checkArrayLen(n int) error {
+	return exceeds(ErrMaxArrayLen, l.maxArrayLen, n)
+}
+
+func (l *limits) checkBulkSize(n int) error {
+	return exceeds(ErrMaxBulkSize, l.maxBulkSize, n)
+}
+
+func (l *limits) checkReplySize(n int) error {
+	return exceeds(ErrMaxReplySize, l.maxReplySize, n)
+}
@@ -0,0 +1,591 @@
+package redisgo
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UnmarshalTypeError describes a reply that can not be stored in a Go value.
+type UnmarshalTypeError struct {
+	Value string       // description of the reply, e.g. `array` or `integer "1"`
+	Type  reflect.Type // type of the Go value it could not be assigned to
+	Field string       // dotted path of the struct field, if any
+	Err   error        // underlying conversion error, if any
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	msg := "redisgo: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+	if e.Field != "" {
+		msg = "redisgo: cannot unmarshal " + e.Value + " into Go struct field " + e.Field + " of type " + e.Type.String()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Marshal converts v to a reply, structs and maps become maps, slices and
+// arrays become arrays. Struct fields are named by their `redis:"name"` tag,
+// `redis:"-"` skips a field and the omitempty option skips zero values.
+func Marshal(v interface{}) (*Resp, error) {
+	r := &Resp{}
+	if err := marshalValue(r, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func marshalValue(r *Resp, v reflect.Value) error {
+	if !v.IsValid() {
+		*r = Resp{Kind: NullKind, Null: true}
+		return nil
+	}
+
+	switch t := v.Type(); {
+	case t == timeType:
+		*r = Resp{Kind: BlukKind, Data: v.Interface().(time.Time).Format(time.RFC3339Nano)}
+		return nil
+	case t.Kind() != reflect.Ptr && t.Implements(textMarshalerType):
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		*r = Resp{Kind: BlukKind, Data: string(text)}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			*r = Resp{Kind: NullKind, Null: true}
+			return nil
+		}
+		return marshalValue(r, v.Elem())
+	case reflect.String:
+		*r = Resp{Kind: BlukKind, Data: v.String()}
+	case reflect.Bool:
+		*r = Resp{Kind: BooleanKind, Data: "f"}
+		if v.Bool() {
+			r.Data = "t"
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		*r = Resp{Kind: IntegerKind, Data: strconv.FormatInt(v.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		*r = Resp{Kind: IntegerKind, Data: strconv.FormatUint(v.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		*r = Resp{Kind: DoubleKind, Data: formatDouble(v.Float(), v.Type().Bits())}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			*r = Resp{Kind: ArrayKind, Null: true}
+			return nil
+		}
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			*r = Resp{Kind: BlukKind, Data: string(v.Bytes())}
+			return nil
+		}
+		array := make([]Resp, v.Len())
+		for i := range array {
+			if err := marshalValue(&array[i], v.Index(i)); err != nil {
+				return err
+			}
+		}
+		*r = Resp{Kind: ArrayKind, Array: array}
+	case reflect.Map:
+		if v.IsNil() {
+			*r = Resp{Kind: NullKind, Null: true}
+			return nil
+		}
+		pairs := make([]RespPair, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var pair RespPair
+			if err := marshalValue(&pair.Key, iter.Key()); err != nil {
+				return err
+			}
+			if err := marshalValue(&pair.Value, iter.Value()); err != nil {
+				return err
+			}
+			pairs = append(pairs, pair)
+		}
+		*r = Resp{Kind: MapKind, Map: pairs}
+	case reflect.Struct:
+		fields := cachedFields(v.Type())
+		pairs := make([]RespPair, 0, len(fields))
+		for _, f := range fields {
+			fv, ok := fieldByIndex(v, f.index, false)
+			if !ok || (f.omitEmpty && fv.IsZero()) {
+				continue
+			}
+			pair := RespPair{Key: Resp{Kind: BlukKind, Data: f.name}}
+			if err := marshalValue(&pair.Value, fv); err != nil {
+				return err
+			}
+			pairs = append(pairs, pair)
+		}
+		*r = Resp{Kind: MapKind, Map: pairs}
+	default:
+		return fmt.Errorf("redisgo: unsupported type: %s", v.Type())
+	}
+	return nil
+}
+
+func formatDouble(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits)
+}
+
+// Unmarshal stores r in the value pointed to by v. Structs and maps are
+// filled from RESP3 maps as well as the flat key/value arrays returned by
+// HGETALL and friends.
+func Unmarshal(r *Resp, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("redisgo: Unmarshal(non-pointer %T)", v)
+	}
+	return unmarshalValue(r, rv.Elem(), "")
+}
+
+func describe(r *Resp) string {
+	switch r.Kind {
+	case ArrayKind, SetKind, PushKind:
+		return "array"
+	case MapKind:
+		return "map"
+	case NullKind:
+		return "null"
+	}
+	data := r.Data
+	if len(data) > 32 {
+		data = data[:32] + "..."
+	}
+	return fmt.Sprintf("%s %q", kindName(r.Kind), data)
+}
+
+func kindName(kind RespKind) string {
+	switch kind {
+	case SimpleKind:
+		return "simple string"
+	case ErrorKind, BlobErrorKind:
+		return "error"
+	case IntegerKind:
+		return "integer"
+	case BlukKind, VerbatimKind:
+		return "bluk string"
+	case DoubleKind:
+		return "double"
+	case BooleanKind:
+		return "boolean"
+	case BigNumberKind:
+		return "big number"
+	}
+	return string(rune(kind))
+}
+
+func unmarshalValue(r *Resp, v reflect.Value, field string) error {
+	mismatch := func(err error) error {
+		return &UnmarshalTypeError{Value: describe(r), Type: v.Type(), Field: field, Err: err}
+	}
+
+	if r.Kind == ErrorKind || r.Kind == BlobErrorKind {
+		return mismatch(nil)
+	}
+
+	// null leaves the value alone, except pointers, maps, slices and interfaces are cleared.
+	if r.Null {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(r, v.Elem(), field)
+	}
+
+	if v.Type() == timeType {
+		t, err := parseTime(r)
+		if err != nil {
+			return mismatch(err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	// pointer receivers are found through the address, values reached by
+	// Unmarshal are always addressable.
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		s, ok := scalar(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return mismatch(err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return mismatch(nil)
+		}
+		x, err := natural(r)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(x))
+	case reflect.String:
+		s, ok := scalar(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		s, ok := scalar(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		b, err := parseBool(s)
+		if err != nil {
+			return mismatch(err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, ok := scalar(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return mismatch(err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s, ok := scalar(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return mismatch(err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		s, ok := scalar(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return mismatch(err)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			s, ok := scalar(r)
+			if !ok {
+				return mismatch(nil)
+			}
+			v.SetBytes([]byte(s))
+			return nil
+		}
+		items, ok := elements(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i := range items {
+			if err := unmarshalValue(&items[i], slice.Index(i), indexPath(field, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		items, ok := elements(r)
+		if !ok || len(items) > v.Len() {
+			return mismatch(nil)
+		}
+		for i := range items {
+			if err := unmarshalValue(&items[i], v.Index(i), indexPath(field, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		pairs, ok := flatPairs(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(pairs)/2))
+		}
+		for i := 0; i < len(pairs); i += 2 {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := unmarshalValue(&pairs[i], key, field); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := unmarshalValue(&pairs[i+1], value, indexPath(field, key.Interface())); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Struct:
+		pairs, ok := flatPairs(r)
+		if !ok {
+			return mismatch(nil)
+		}
+		fields := cachedFields(v.Type())
+		for i := 0; i < len(pairs); i += 2 {
+			name, ok := scalar(&pairs[i])
+			if !ok {
+				return mismatch(nil)
+			}
+			f := fields.lookup(name)
+			if f == nil {
+				continue
+			}
+			path := f.name
+			if field != "" {
+				path = field + "." + f.name
+			}
+			fv, ok := fieldByIndex(v, f.index, true)
+			if !ok {
+				return fmt.Errorf("redisgo: cannot set %s through a nil pointer to an unexported embedded struct", path)
+			}
+			if err := unmarshalValue(&pairs[i+1], fv, path); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch(nil)
+	}
+	return nil
+}
+
+func indexPath(field string, index interface{}) string {
+	return fmt.Sprintf("%s[%v]", field, index)
+}
+
+// scalar returns the textual value of simple replies.
+func scalar(r *Resp) (string, bool) {
+	switch r.Kind {
+	case SimpleKind, BlukKind, IntegerKind, DoubleKind, BigNumberKind, BooleanKind:
+		return r.Data, true
+	case VerbatimKind:
+		// the txt: or mkd: prefix, a hand-built reply may lack it.
+		if len(r.Data) >= 4 && r.Data[3] == ':' {
+			return r.Data[4:], true
+		}
+		return r.Data, true
+	}
+	return "", false
+}
+
+func elements(r *Resp) ([]Resp, bool) {
+	switch r.Kind {
+	case ArrayKind, SetKind, PushKind:
+		return r.Array, true
+	}
+	return nil, false
+}
+
+func flatPairs(r *Resp) ([]Resp, bool) {
+	switch r.Kind {
+	case MapKind:
+		flat := make([]Resp, 0, 2*len(r.Map))
+		for _, pair := range r.Map {
+			flat = append(flat, pair.Key, pair.Value)
+		}
+		return flat, true
+	case ArrayKind, SetKind:
+		return r.Array, len(r.Array)%2 == 0
+	}
+	return nil, false
+}
+
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "t", "1", "true", "TRUE", "True":
+		return true, nil
+	case "f", "0", "false", "FALSE", "False", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean: %q", s)
+}
+
+// parseTime accepts RFC 3339 strings and unix timestamps in seconds.
+func parseTime(r *Resp) (time.Time, error) {
+	s, ok := scalar(r)
+	if !ok {
+		return time.Time{}, fmt.Errorf("not a scalar")
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// natural converts r to string, int64, float64, bool, nil,
+// []interface{} or map[string]interface{}.
+func natural(r *Resp) (interface{}, error) {
+	var x interface{}
+	switch r.Kind {
+	case IntegerKind:
+		n, err := strconv.ParseInt(r.Data, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		x = n
+	case DoubleKind:
+		f, err := strconv.ParseFloat(r.Data, 64)
+		if err != nil {
+			return nil, err
+		}
+		x = f
+	case BooleanKind:
+		x = r.Data == "t"
+	case MapKind:
+		m := make(map[string]interface{}, len(r.Map))
+		for i := range r.Map {
+			key, ok := scalar(&r.Map[i].Key)
+			if !ok {
+				return nil, &UnmarshalTypeError{Value: describe(&r.Map[i].Key), Type: reflect.TypeOf("")}
+			}
+			if err := unmarshalValue(&r.Map[i].Value, reflect.ValueOf(&x).Elem(), ""); err != nil {
+				return nil, err
+			}
+			m[key], x = x, nil
+		}
+		x = m
+	case ArrayKind, SetKind, PushKind:
+		array := make([]interface{}, len(r.Array))
+		for i := range r.Array {
+			if err := unmarshalValue(&r.Array[i], reflect.ValueOf(array).Index(i), ""); err != nil {
+				return nil, err
+			}
+		}
+		x = array
+	default:
+		x, _ = scalar(r)
+	}
+	return x, nil
+}
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+type fields []field
+
+func (fs fields) lookup(name string) *field {
+	for i := range fs {
+		if fs[i].name == name {
+			return &fs[i]
+		}
+	}
+	for i := range fs {
+		if strings.EqualFold(fs[i].name, name) {
+			return &fs[i]
+		}
+	}
+	return nil
+}
+
+var fieldCache sync.Map // reflect.Type - fields
+
+func cachedFields(t reflect.Type) fields {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.(fields)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t, nil, nil))
+	return fs.(fields)
+}
+
+// typeFields lists the fields of t, embedded holds the structs being
+// flattened, so that a struct embedding a pointer to itself is flattened
+// once, as encoding/json does.
+func typeFields(t reflect.Type, index []int, embedded map[reflect.Type]bool) fields {
+	if embedded[t] {
+		return nil
+	}
+	if embedded == nil {
+		embedded = make(map[reflect.Type]bool)
+	}
+	embedded[t] = true
+	defer delete(embedded, t)
+
+	var fs fields
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("redis")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(append([]int(nil), index...), i)
+
+		// untagged embedded structs are flattened into the parent.
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fs = append(fs, typeFields(ft, idx, embedded)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fs = append(fs, field{name: name, index: idx, omitEmpty: opts == "omitempty"})
+	}
+	return fs
+}
+
+// fieldByIndex walks embedded pointers, allocating them if alloc is set.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
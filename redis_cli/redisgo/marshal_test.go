@@ -0,0 +1,195 @@
+package redisgo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Base struct {
+	ID int64 `redis:"id"`
+}
+
+type User struct {
+	Base
+	Name     string            `redis:"name"`
+	Age      uint8             `redis:"age"`
+	Score    float64           `redis:"score"`
+	Admin    bool              `redis:"admin"`
+	Created  time.Time         `redis:"created"`
+	Nickname *string           `redis:"nickname,omitempty"`
+	Tags     []string          `redis:"tags,omitempty"`
+	Extra    map[string]string `redis:"extra,omitempty"`
+	Secret   string            `redis:"-"`
+	internal int
+}
+
+func decodeString(t *testing.T, input string) *Resp {
+	r := &Resp{}
+	if err := NewDecoder(strings.NewReader(input), 1024).Decode(r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestMarshal(t *testing.T) {
+	created := time.Date(2019, 7, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    string
+		wantErr bool
+	}{
+		{"nil", nil, "_\r\n", false},
+		{"string", "hello", "$5\r\nhello\r\n", false},
+		{"bytes", []byte("hi"), "$2\r\nhi\r\n", false},
+		{"int", -42, ":-42\r\n", false},
+		{"float", 1.5, ",1.5\r\n", false},
+		{"bool", true, "#t\r\n", false},
+		{"slice", []interface{}{1, "a", nil}, "*3\r\n:1\r\n$1\r\na\r\n_\r\n", false},
+		{"map", map[string]int{"a": 1}, "%1\r\n$1\r\na\r\n:1\r\n", false},
+		{"struct", User{Base: Base{7}, Name: "go", Age: 3, Score: 0.5, Admin: true, Created: created, Secret: "x"},
+			"%6\r\n$2\r\nid\r\n:7\r\n$4\r\nname\r\n$2\r\ngo\r\n$3\r\nage\r\n:3\r\n$5\r\nscore\r\n,0.5\r\n" +
+				"$5\r\nadmin\r\n#t\r\n$7\r\ncreated\r\n$20\r\n2019-07-01T08:00:00Z\r\n", false},
+		{"unsupported", make(chan int), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("Marshal() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	created := time.Date(2019, 7, 1, 8, 0, 0, 0, time.UTC)
+	nickname := "gopher"
+	want := User{Base: Base{7}, Name: "go", Age: 3, Score: 0.5, Admin: true, Created: created,
+		Nickname: &nickname, Tags: []string{"a", "b"}, Extra: map[string]string{"k": "v"}}
+
+	// HGETALL style flat array.
+	var flat User
+	err := Unmarshal(decodeString(t, "*16\r\n$2\r\nid\r\n$1\r\n7\r\n$4\r\nname\r\n$2\r\ngo\r\n$3\r\nage\r\n$1\r\n3\r\n"+
+		"$5\r\nscore\r\n$3\r\n0.5\r\n$5\r\nadmin\r\n$1\r\n1\r\n$7\r\ncreated\r\n$10\r\n1561968000\r\n"+
+		"$8\r\nnickname\r\n$6\r\ngopher\r\n$7\r\nunknown\r\n$1\r\nx\r\n"), &flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat.Created = flat.Created.UTC()
+	flat.Tags, flat.Extra = want.Tags, want.Extra
+	if !reflect.DeepEqual(flat, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", flat, want)
+	}
+
+	// RESP3 map round-trip.
+	r, err := Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got User
+	if err = Unmarshal(r, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+
+	// natural Go values.
+	var x interface{}
+	if err = Unmarshal(decodeString(t, "%2\r\n+a\r\n:1\r\n+b\r\n*2\r\n,2.5\r\n#f\r\n"), &x); err != nil {
+		t.Fatal(err)
+	}
+	if wantX := map[string]interface{}{"a": int64(1), "b": []interface{}{2.5, false}}; !reflect.DeepEqual(x, wantX) {
+		t.Errorf("Unmarshal() = %#v, want %#v", x, wantX)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	var user User
+	err := Unmarshal(decodeString(t, "*2\r\n$3\r\nage\r\n$3\r\n300\r\n"), &user)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "age" || typeErr.Type.Kind() != reflect.Uint8 {
+		t.Errorf("Unmarshal() error = %v, want age overflow", err)
+	}
+
+	var n int
+	if err = Unmarshal(decodeString(t, "*1\r\n:1\r\n"), &n); !errors.As(err, &typeErr) {
+		t.Errorf("Unmarshal() error = %v, want UnmarshalTypeError", err)
+	}
+	if err = Unmarshal(decodeString(t, "-ERR oops\r\n"), &n); err == nil {
+		t.Errorf("Unmarshal() of error reply expect error")
+	}
+	if err = Unmarshal(decodeString(t, ":1\r\n"), n); err == nil {
+		t.Errorf("Unmarshal() into non-pointer expect error")
+	}
+	if err = Unmarshal(decodeString(t, "*3\r\n+a\r\n+b\r\n+c\r\n"), &user); err == nil {
+		t.Errorf("Unmarshal() odd array into struct expect error")
+	}
+}
+
+// level unmarshals with a pointer receiver.
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type inner struct {
+	Name string `redis:"name"`
+}
+
+type Outer struct {
+	*inner
+	Level  level            `redis:"level"`
+	Levels []level          `redis:"levels"`
+	ByName map[string]level `redis:"by_name"`
+}
+
+type Node struct {
+	*Node
+	Value string `redis:"value"`
+}
+
+func TestUnmarshal_Edges(t *testing.T) {
+	// pointer receivers on fields, slice elements and map values.
+	var outer Outer
+	err := Unmarshal(decodeString(t, "%3\r\n+level\r\n+high\r\n+levels\r\n*2\r\n+low\r\n+high\r\n+by_name\r\n%1\r\n+a\r\n+low\r\n"), &outer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Outer{Level: 2, Levels: []level{1, 2}, ByName: map[string]level{"a": 1}}); !reflect.DeepEqual(outer, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", outer, want)
+	}
+
+	// a field promoted through a nil unexported pointer cannot be set.
+	if err = Unmarshal(decodeString(t, "%1\r\n+name\r\n+go\r\n"), &outer); err == nil || !strings.Contains(err.Error(), "name") {
+		t.Errorf("Unmarshal() error = %v, want cannot set name", err)
+	}
+
+	// a verbatim string without its format prefix.
+	var s string
+	if err = Unmarshal(&Resp{Kind: VerbatimKind, Data: "ab"}, &s); err != nil || s != "ab" {
+		t.Errorf("Unmarshal() = %q, %v, want ab", s, err)
+	}
+
+	// a struct embedding a pointer to itself.
+	var node Node
+	if err = Unmarshal(decodeString(t, "%1\r\n+value\r\n+v\r\n"), &node); err != nil || node.Value != "v" {
+		t.Errorf("Unmarshal() = %+v, %v, want value v", node, err)
+	}
+}
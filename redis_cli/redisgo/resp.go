@@ -10,13 +10,32 @@ const (
 	IntegerKind          = ':'
 	BlukKind             = '$'
 	ArrayKind            = '*'
+
+	// RESP3 kinds
+	NullKind      = '_'
+	DoubleKind    = ','
+	BooleanKind   = '#'
+	BigNumberKind = '('
+	BlobErrorKind = '!'
+	VerbatimKind  = '='
+	MapKind       = '%'
+	SetKind       = '~'
+	AttributeKind = '|'
+	PushKind      = '>'
 )
 
 type Resp struct {
 	Kind  RespKind
 	Null  bool
 	Data  string
-	Array []Resp
+	Array []Resp     // ArrayKind, SetKind, PushKind
+	Map   []RespPair // MapKind
+	Attrs []RespPair // attributes sent ahead of this reply
+}
+
+type RespPair struct {
+	Key   Resp
+	Value Resp
 }
 
 func (r *Resp) String() string {
@@ -0,0 +1,335 @@
+package redisgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"unsafe"
+)
+
+// aggregate is an array, set, push, map or attribute whose elements
+// are still arriving.
+type aggregate struct {
+	kind  RespKind
+	n     int    // number of elements expected, maps count keys and values
+	items []Resp // elements received so far
+	attrs []RespPair
+	nattr int // attributes waiting for the next element
+}
+
+// StreamDecoder is a push-style decoder, it accepts arbitrary chunks of
+// bytes and keeps the parse state of partial replies between calls.
+type StreamDecoder struct {
+	buf         []byte
+	off         int
+	maxLineSize int
+	bulk        int // payload length of the pending bulk, -1 if none
+	bulkKind    RespKind
+	stack       []*aggregate
+	attrs       []RespPair // attributes waiting for a top level reply
+	nattr       int        // attributes waiting for a top level reply
+	attrDepth   int        // attributes waiting at any level, each nests
+	err         error
+	limits      limits
+	read        int // bytes of the partial reply consumed so far
+
+	// reply under construction by FeedReplies, nil for Feed.
+	reply *Reply
+	free  []*aggregate
+}
+
+// NewStreamDecoder returns a decoder for chunked input, options set the
+// limits of the replies it accepts.
+func NewStreamDecoder(maxLineSize int, options ...DecoderOption) *StreamDecoder {
+	return &StreamDecoder{
+		maxLineSize: maxLineSize,
+		bulk:        -1,
+		limits:      newLimits(options),
+	}
+}
+
+// Buffered returns the number of bytes held by a partial reply.
+func (d *StreamDecoder) Buffered() int {
+	return len(d.buf) - d.off
+}
+
+// Reset discards any partial reply and the sticky error.
+func (d *StreamDecoder) Reset() {
+	d.buf = d.buf[:0]
+	d.off = 0
+	d.bulk = -1
+	d.stack = d.stack[:0]
+	d.attrs = nil
+	d.nattr, d.attrDepth = 0, 0
+	d.err = nil
+	d.read = 0
+	if d.reply != nil {
+		d.reply.Release()
+		d.reply = nil
+	}
+}
+
+// errMixedFeed is returned by Feed in the middle of a reply started by
+// FeedReplies, part of which is in pooled buffers already.
+var errMixedFeed = errors.New("Feed in the middle of a reply started by FeedReplies")
+
+// Feed appends p to the decoder and returns every reply completed by it.
+// Once an error is returned the decoder must be Reset before reuse.
+func (d *StreamDecoder) Feed(p []byte) ([]Resp, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	// the replies of Feed must not share the buffers of a pooled reply.
+	if d.reply != nil {
+		if len(d.stack) > 0 || d.bulk >= 0 || d.nattr > 0 {
+			d.err = errMixedFeed
+			return nil, d.err
+		}
+		d.reply.Release()
+		d.reply = nil
+	}
+
+	var out []Resp
+	err := d.feed(p, func(r Resp) {
+		out = append(out, r)
+	})
+	return out, err
+}
+
+// FeedReplies is Feed for replies decoded into pooled buffers, each of
+// them must be released once it is no longer used. Feed fails in the
+// middle of a reply started by FeedReplies.
+func (d *StreamDecoder) FeedReplies(p []byte) ([]*Reply, error) {
+	var out []*Reply
+	if d.reply == nil {
+		d.reply = newReply()
+	}
+	err := d.feed(p, func(r Resp) {
+		d.reply.Resp = r
+		out = append(out, d.reply)
+		d.reply = newReply()
+	})
+	return out, err
+}
+
+func (d *StreamDecoder) feed(p []byte, emit func(r Resp)) error {
+	if d.err != nil {
+		return d.err
+	}
+	// make room by dropping consumed bytes rather than growing the buffer.
+	if d.off > 0 && len(d.buf)+len(p) > cap(d.buf) {
+		d.compact()
+	}
+	d.buf = append(d.buf, p...)
+
+	for {
+		r, ok, err := d.next()
+		if err != nil {
+			d.err = err
+			return err
+		}
+		if !ok {
+			break
+		}
+		if r, ok = d.complete(r); ok {
+			emit(r)
+			d.read = 0
+		}
+	}
+
+	// drop consumed bytes once they take half of the buffer, a large bulk
+	// arriving in small chunks is not copied over and over.
+	if d.off > len(d.buf)/2 {
+		d.compact()
+	}
+	return nil
+}
+
+func (d *StreamDecoder) compact() {
+	d.buf = d.buf[:copy(d.buf, d.buf[d.off:])]
+	d.off = 0
+}
+
+// arena returns the buffers of the reply under construction, if any.
+func (d *StreamDecoder) arena() *arena {
+	if d.reply == nil {
+		return nil
+	}
+	return &d.reply.arena
+}
+
+// next consumes one scalar value or a complete empty aggregate, ok is
+// false if the buffer does not hold enough bytes yet. Aggregate headers
+// are pushed on the stack and parsing goes on, without recursion.
+func (d *StreamDecoder) next() (r Resp, ok bool, err error) {
+	for {
+		if d.bulk >= 0 {
+			return d.nextBulk()
+		}
+
+		i := bytes.IndexByte(d.buf[d.off:], '\n')
+		if i < 0 {
+			if d.maxLineSize > 0 && len(d.buf)-d.off > d.maxLineSize {
+				return r, false, fmt.Errorf("line exceeds %d bytes", d.maxLineSize)
+			}
+			return r, false, nil
+		}
+		ln := d.buf[d.off : d.off+i+1]
+		if len(ln) < 3 || ln[len(ln)-2] != '\r' {
+			return r, false, fmt.Errorf("expect terminated with CRLF")
+		}
+		d.off += len(ln)
+		d.read += len(ln)
+		if err = d.limits.checkReplySize(d.read); err != nil {
+			return r, false, err
+		}
+		kind, ln := RespKind(ln[0]), ln[1:len(ln)-2]
+
+		switch kind {
+		case SimpleKind, ErrorKind, IntegerKind, DoubleKind, BigNumberKind:
+			return Resp{Kind: kind, Data: makeString(d.arena(), ln)}, true, nil
+		case BooleanKind:
+			if len(ln) != 1 || (ln[0] != 't' && ln[0] != 'f') {
+				return r, false, fmt.Errorf("invalid boolean: %q", ln)
+			}
+			return Resp{Kind: kind, Data: makeString(d.arena(), ln)}, true, nil
+		case NullKind:
+			if len(ln) != 0 {
+				return r, false, fmt.Errorf("invalid null: %q", ln)
+			}
+			return Resp{Kind: kind, Null: true}, true, nil
+		case BlukKind, BlobErrorKind, VerbatimKind:
+			n, err := strconv.Atoi(*(*string)(unsafe.Pointer(&ln)))
+			if err != nil {
+				return r, false, err
+			}
+			if n < -1 {
+				return r, false, fmt.Errorf("invalid bluk length: %d", n)
+			}
+			if err = d.limits.checkBulkSize(n); err != nil {
+				return r, false, err
+			}
+			// refuse the bulk before buffering it.
+			if n >= 0 {
+				if err = d.limits.checkReplySize(d.read + n + 2); err != nil {
+					return r, false, err
+				}
+			}
+			if n == -1 {
+				return Resp{Kind: kind, Null: true}, true, nil
+			}
+			d.bulk, d.bulkKind = n, kind
+		case ArrayKind, SetKind, PushKind, MapKind, AttributeKind:
+			n, err := strconv.Atoi(*(*string)(unsafe.Pointer(&ln)))
+			if err != nil {
+				return r, false, err
+			}
+			if n == -1 && (kind == ArrayKind || kind == SetKind || kind == PushKind) {
+				return Resp{Kind: kind, Null: true}, true, nil
+			}
+			if n < 0 {
+				return r, false, fmt.Errorf("invalid aggregate length: %d", n)
+			}
+			if err = d.limits.checkArrayLen(n); err != nil {
+				return r, false, err
+			}
+			// values described by attributes nest one level deeper, as
+			// they do for Decoder.
+			if err = d.limits.checkDepth(len(d.stack) + d.attrDepth + 1); err != nil {
+				return r, false, err
+			}
+			if kind == MapKind || kind == AttributeKind {
+				n *= 2
+			}
+			if n == 0 {
+				return d.build(d.newAggregate(kind, 0)), true, nil
+			}
+			d.stack = append(d.stack, d.newAggregate(kind, n))
+		default:
+			return r, false, fmt.Errorf("unrecognized kind: %c", kind)
+		}
+	}
+}
+
+// nextBulk consumes the payload of the pending bulk.
+func (d *StreamDecoder) nextBulk() (r Resp, ok bool, err error) {
+	if len(d.buf)-d.off < d.bulk+2 {
+		return r, false, nil
+	}
+	data := d.buf[d.off : d.off+d.bulk+2]
+	if data[len(data)-2] != '\r' || data[len(data)-1] != '\n' {
+		return r, false, fmt.Errorf("expect terminated with CRLF")
+	}
+	d.read += len(data)
+	r = Resp{Kind: d.bulkKind, Data: makeString(d.arena(), data[:len(data)-2])}
+	if r.Kind == VerbatimKind && (len(r.Data) < 4 || r.Data[3] != ':') {
+		return r, false, fmt.Errorf("invalid verbatim string: %q", r.Data)
+	}
+	d.off += len(data)
+	d.bulk = -1
+	return r, true, nil
+}
+
+// complete attaches r to its enclosing aggregate, ok is true when r
+// turns out to be a complete top level reply.
+func (d *StreamDecoder) complete(r Resp) (Resp, bool) {
+	for {
+		attrs, nattr := &d.attrs, &d.nattr
+		if len(d.stack) > 0 {
+			top := d.stack[len(d.stack)-1]
+			attrs, nattr = &top.attrs, &top.nattr
+		}
+
+		// attributes describe the value that follows them.
+		if r.Kind == AttributeKind {
+			*attrs = append(*attrs, r.Map...)
+			*nattr++
+			d.attrDepth++
+			return Resp{}, false
+		}
+		if len(*attrs) > 0 {
+			r.Attrs = append(*attrs, r.Attrs...)
+			*attrs = nil
+		}
+		d.attrDepth -= *nattr
+		*nattr = 0
+
+		if len(d.stack) == 0 {
+			return r, true
+		}
+		top := d.stack[len(d.stack)-1]
+		top.items = append(top.items, r)
+		if len(top.items) < top.n {
+			return Resp{}, false
+		}
+		d.stack = d.stack[:len(d.stack)-1]
+		r = d.build(top)
+	}
+}
+
+func (d *StreamDecoder) newAggregate(kind RespKind, n int) *aggregate {
+	var a *aggregate
+	if len(d.free) > 0 {
+		a, d.free = d.free[len(d.free)-1], d.free[:len(d.free)-1]
+	} else {
+		a = &aggregate{}
+	}
+	*a = aggregate{kind: kind, n: n, items: makeResps(d.arena(), n)}
+	return a
+}
+
+// build turns a complete aggregate into a reply, the aggregate is reused.
+func (d *StreamDecoder) build(a *aggregate) Resp {
+	r := Resp{Kind: a.kind, Array: a.items}
+	if a.kind == MapKind || a.kind == AttributeKind {
+		pairs := makePairs(d.arena(), len(a.items)/2)
+		for i := 0; i < len(a.items); i += 2 {
+			pairs = append(pairs, RespPair{Key: a.items[i], Value: a.items[i+1]})
+		}
+		r = Resp{Kind: a.kind, Map: pairs}
+	}
+	*a = aggregate{}
+	d.free = append(d.free, a)
+	return r
+}
@@ -0,0 +1,167 @@
+package redisgo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamDecoder_Feed(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"simple", "+OK\r\n", []string{"+OK\r\n"}, false},
+		{"many", "+OK\r\n:1\r\n$-1\r\n", []string{"+OK\r\n", ":1\r\n", "$-1\r\n"}, false},
+		{"bluk", "$7\r\nfoo\nbar\r\n", []string{"$7\r\nfoo\nbar\r\n"}, false},
+		{"empty-array", "*0\r\n+OK\r\n", []string{"*0\r\n", "+OK\r\n"}, false},
+		{"array-in-array", "*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n", []string{"*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n-Bar\r\n"}, false},
+		{"map", "%2\r\n+first\r\n:1\r\n+second\r\n*1\r\n#t\r\n", []string{"%2\r\n+first\r\n:1\r\n+second\r\n*1\r\n#t\r\n"}, false},
+		{"attribute", "|1\r\n+ttl\r\n:10\r\n*2\r\n|1\r\n+a\r\n_\r\n:1\r\n:2\r\n", []string{"|1\r\n+ttl\r\n:10\r\n*2\r\n|1\r\n+a\r\n_\r\n:1\r\n:2\r\n"}, false},
+		{"push", ">2\r\n+message\r\n=7\r\ntxt:abc\r\n", []string{">2\r\n+message\r\n=7\r\ntxt:abc\r\n"}, false},
+		{"bad-crlf", "$3\r\nfooXX", nil, true},
+		{"unknown", "+OK\r\n?\r\n", []string{"+OK\r\n"}, true},
+	}
+	for _, tt := range tests {
+		// whole input, then one byte at a time.
+		for _, size := range []int{len(tt.input), 1} {
+			t.Run(tt.name, func(t *testing.T) {
+				d := NewStreamDecoder(1024)
+				var got []string
+				var err error
+				for i := 0; i < len(tt.input) && err == nil; i += size {
+					var rs []Resp
+					rs, err = d.Feed([]byte(tt.input[i:min(i+size, len(tt.input))]))
+					for i := range rs {
+						got = append(got, rs[i].String())
+					}
+				}
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("StreamDecoder.Feed() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+					t.Errorf("StreamDecoder.Feed() = %q, want %q", got, tt.want)
+				}
+				if err == nil && d.Buffered() != 0 {
+					t.Errorf("StreamDecoder.Buffered() = %d, want 0", d.Buffered())
+				}
+			})
+		}
+	}
+}
+
+func TestStreamDecoder_Partial(t *testing.T) {
+	d := NewStreamDecoder(1024)
+	if rs, err := d.Feed([]byte("*2\r\n$5\r\nhel")); err != nil || len(rs) != 0 {
+		t.Fatalf("StreamDecoder.Feed() = %v, %v", rs, err)
+	}
+	if d.Buffered() != 3 {
+		t.Errorf("StreamDecoder.Buffered() = %d, want 3", d.Buffered())
+	}
+	rs, err := d.Feed([]byte("lo\r\n:1\r\n+OK\r\n"))
+	if err != nil || len(rs) != 2 {
+		t.Fatalf("StreamDecoder.Feed() = %v, %v", rs, err)
+	}
+	if got := rs[0].String(); got != "*2\r\n$5\r\nhello\r\n:1\r\n" {
+		t.Errorf("StreamDecoder.Feed() = %q", got)
+	}
+}
+
+func TestStreamDecoder_MaxLineSize(t *testing.T) {
+	d := NewStreamDecoder(8)
+	if _, err := d.Feed([]byte("+0123456789")); err == nil {
+		t.Errorf("StreamDecoder.Feed() expect line size error")
+	}
+	d.Reset()
+	if rs, err := d.Feed([]byte("+OK\r\n")); err != nil || len(rs) != 1 {
+		t.Errorf("StreamDecoder.Feed() after Reset = %v, %v", rs, err)
+	}
+}
+
+func TestStreamDecoder_Mixed(t *testing.T) {
+	d := NewStreamDecoder(1024)
+	replies, err := d.FeedReplies([]byte("$3\r\nabc\r\n"))
+	if err != nil || len(replies) != 1 {
+		t.Fatalf("StreamDecoder.FeedReplies() = %v, %v", replies, err)
+	}
+	replies[0].Release()
+
+	// replies of Feed stay valid once pooled replies are released and reused.
+	rs, err := d.Feed([]byte("$3\r\nfoo\r\n"))
+	if err != nil || len(rs) != 1 {
+		t.Fatalf("StreamDecoder.Feed() = %v, %v", rs, err)
+	}
+	for i := 0; i < 4; i++ {
+		replies, err = d.FeedReplies([]byte("$3\r\nxyz\r\n"))
+		if err != nil || len(replies) != 1 {
+			t.Fatalf("StreamDecoder.FeedReplies() = %v, %v", replies, err)
+		}
+		replies[0].Release()
+	}
+	if rs[0].Data != "foo" {
+		t.Errorf("StreamDecoder.Feed() reply = %q after releasing pooled replies, want %q", rs[0].Data, "foo")
+	}
+
+	// a reply started by FeedReplies cannot be finished by Feed.
+	if replies, err = d.FeedReplies([]byte("*2\r\n:1\r\n")); err != nil || len(replies) != 0 {
+		t.Fatalf("StreamDecoder.FeedReplies() = %v, %v", replies, err)
+	}
+	if _, err = d.Feed([]byte(":2\r\n")); err == nil {
+		t.Errorf("StreamDecoder.Feed() in the middle of a pooled reply expect error")
+	}
+}
+
+func TestStreamDecoder_DeepNesting(t *testing.T) {
+	// with the depth limit disabled nesting is only bounded by memory.
+	d := NewStreamDecoder(1024, WithMaxDepth(0))
+	rs, err := d.Feed([]byte(strings.Repeat("*1\r\n", 1<<20) + ":1\r\n"))
+	if err != nil || len(rs) != 1 {
+		t.Fatalf("StreamDecoder.Feed() = %d replies, %v", len(rs), err)
+	}
+}
+
+func BenchmarkStreamDecoder_Feed(b *testing.B) {
+	d := NewStreamDecoder(4096)
+	b.SetBytes(int64(len(benchReply)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if rs, err := d.Feed(benchReply); err != nil || len(rs) != 1 {
+			b.Fatal(rs, err)
+		}
+	}
+}
+
+func BenchmarkStreamDecoder_FeedReplies(b *testing.B) {
+	d := NewStreamDecoder(4096)
+	b.SetBytes(int64(len(benchReply)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rs, err := d.FeedReplies(benchReply)
+		if err != nil || len(rs) != 1 {
+			b.Fatal(rs, err)
+		}
+		rs[0].Release()
+	}
+}
+
+func BenchmarkStreamDecoder_FeedChunks(b *testing.B) {
+	// a 1 MB bulk string arriving in 1 KB chunks.
+	input := []byte("$1048576\r\n" + strings.Repeat("x", 1<<20) + "\r\n+OK\r\n")
+	d := NewStreamDecoder(4096)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var n int
+		for off := 0; off < len(input); off += 1024 {
+			rs, err := d.Feed(input[off:min(off+1024, len(input))])
+			if err != nil {
+				b.Fatal(err)
+			}
+			n += len(rs)
+		}
+		if n != 2 {
+			b.Fatalf("StreamDecoder.Feed() = %d replies, want 2", n)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// commandHandler executes commands for one connection.
+type commandHandler struct {
+	keyspace *keyspace
+	db       int
+	name     string
+}
+
+func (h *commandHandler) HandleActive(ctx netty.ActiveContext) {
+	fmt.Println("client connected:", ctx.Channel().RemoteAddr())
+	ctx.HandleActive()
+}
+
+func (h *commandHandler) HandleRead(ctx netty.InboundContext, message netty.Message) {
+
+	args := message.([]string)
+
+	// execute command & post reply.
+	ctx.Write(h.keyspace.execute(h, args))
+
+	if strings.EqualFold(args[0], "quit") {
+		ctx.Close(nil)
+	}
+}
+
+func (h *commandHandler) HandleInactive(ctx netty.InactiveContext, ex netty.Exception) {
+	fmt.Println("client disconnected:", ctx.Channel().RemoteAddr(), ex)
+	ctx.HandleInactive(ex)
+}
+
+// execute looks up the command, checks its arity and runs it with the keyspace locked.
+func (ks *keyspace) execute(h *commandHandler, args []string) *redisgo.Resp {
+
+	name := strings.ToLower(args[0])
+	cmd, ok := commands[name]
+	if !ok {
+		var quoted []string
+		for _, arg := range args[1:] {
+			quoted = append(quoted, "'"+arg+"'")
+		}
+		return errorReply("ERR unknown command '%s', with args beginning with: %s", args[0], strings.Join(quoted, " "))
+	}
+
+	if (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity {
+		return errorReply("ERR wrong number of arguments for '%s' command", name)
+	}
+
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	return cmd.fn(h, ks.dbs[h.db], args)
+}
@@ -0,0 +1,970 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+type command struct {
+	// arity is the exact number of arguments including the command name,
+	// or the negated minimum if it is variadic.
+	arity int
+	fn    func(h *commandHandler, db *database, args []string) *redisgo.Resp
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		// connection & server
+		"ping":     {-1, ping},
+		"echo":     {2, echo},
+		"select":   {2, selectDB},
+		"quit":     {1, quit},
+		"client":   {-2, client},
+		"command":  {-1, commandInfo},
+		"info":     {-1, info},
+		"dbsize":   {1, dbsize},
+		"flushdb":  {-1, flushdb},
+		"flushall": {-1, flushall},
+
+		// keys
+		"del":     {-2, del},
+		"exists":  {-2, exists},
+		"type":    {2, typeOf},
+		"keys":    {2, keys},
+		"expire":  {3, expire},
+		"pexpire": {3, expire},
+		"ttl":     {2, ttl},
+		"pttl":    {2, ttl},
+		"persist": {2, persist},
+
+		// strings
+		"get":    {2, get},
+		"set":    {-3, set},
+		"mget":   {-2, mget},
+		"mset":   {-3, mset},
+		"incr":   {2, incrBy},
+		"decr":   {2, incrBy},
+		"incrby": {3, incrBy},
+		"decrby": {3, incrBy},
+		"append": {3, appendString},
+		"strlen": {2, strlen},
+
+		// hashes
+		"hset":    {-4, hset},
+		"hget":    {3, hget},
+		"hmget":   {-3, hmget},
+		"hdel":    {-3, hdel},
+		"hgetall": {2, hgetall},
+		"hkeys":   {2, hgetall},
+		"hvals":   {2, hgetall},
+		"hlen":    {2, hlen},
+		"hexists": {3, hexists},
+		"hincrby": {4, hincrby},
+
+		// lists
+		"lpush":  {-3, push},
+		"rpush":  {-3, push},
+		"lpop":   {2, pop},
+		"rpop":   {2, pop},
+		"llen":   {2, llen},
+		"lindex": {3, lindex},
+		"lrange": {4, lrange},
+
+		// sets
+		"sadd":      {-3, sadd},
+		"srem":      {-3, srem},
+		"smembers":  {2, smembers},
+		"sismember": {3, sismember},
+		"scard":     {2, scard},
+
+		// sorted sets
+		"zadd":    {-4, zadd},
+		"zrem":    {-3, zrem},
+		"zscore":  {3, zscore},
+		"zcard":   {2, zcard},
+		"zincrby": {4, zincrby},
+		"zrange":  {-4, zrange},
+	}
+}
+
+// replies
+func okReply() *redisgo.Resp {
+	return simpleReply("OK")
+}
+
+func simpleReply(s string) *redisgo.Resp {
+	return &redisgo.Resp{Kind: redisgo.SimpleKind, Data: s}
+}
+
+func errorReply(format string, args ...interface{}) *redisgo.Resp {
+	return &redisgo.Resp{Kind: redisgo.ErrorKind, Data: fmt.Sprintf(format, args...)}
+}
+
+func intReply(n int64) *redisgo.Resp {
+	return &redisgo.Resp{Kind: redisgo.IntegerKind, Data: strconv.FormatInt(n, 10)}
+}
+
+func boolReply(b bool) *redisgo.Resp {
+	if b {
+		return intReply(1)
+	}
+	return intReply(0)
+}
+
+func bulkReply(s string) *redisgo.Resp {
+	return &redisgo.Resp{Kind: redisgo.BlukKind, Data: s}
+}
+
+func nullReply() *redisgo.Resp {
+	return &redisgo.Resp{Kind: redisgo.BlukKind, Null: true}
+}
+
+func arrayReply(items []string) *redisgo.Resp {
+	array := make([]redisgo.Resp, len(items))
+	for i, item := range items {
+		array[i] = redisgo.Resp{Kind: redisgo.BlukKind, Data: item}
+	}
+	return &redisgo.Resp{Kind: redisgo.ArrayKind, Array: array}
+}
+
+var (
+	wrongTypeErr  = errorReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	notIntegerErr = errorReply("ERR value is not an integer or out of range")
+	notFloatErr   = errorReply("ERR value is not a valid float")
+	syntaxErr     = errorReply("ERR syntax error")
+	overflowErr   = errorReply("ERR increment or decrement would overflow")
+)
+
+// lookupAs returns the value of key as T, a missing key yields the zero T.
+func lookupAs[T any](db *database, key string) (T, bool, *redisgo.Resp) {
+	var zero T
+	v, ok := db.lookup(key)
+	if !ok {
+		return zero, false, nil
+	}
+	t, ok := v.(T)
+	if !ok {
+		return zero, false, wrongTypeErr
+	}
+	return t, true, nil
+}
+
+// addInt64 returns n+delta, ok is false if it overflows.
+func addInt64(n, delta int64) (int64, bool) {
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return n, false
+	}
+	return n + delta, true
+}
+
+func formatScore(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// normalizeRange maps redis style inclusive, possibly negative, indexes to a slice range.
+func normalizeRange(start, stop int64, n int) (int, int) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+	if start > stop {
+		return 0, 0
+	}
+	return int(start), int(stop) + 1
+}
+
+// connection & server
+
+func ping(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	switch len(args) {
+	case 1:
+		return simpleReply("PONG")
+	case 2:
+		return bulkReply(args[1])
+	}
+	return errorReply("ERR wrong number of arguments for 'ping' command")
+}
+
+func echo(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	return bulkReply(args[1])
+}
+
+func selectDB(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		return notIntegerErr
+	}
+	if index < 0 || index >= len(h.keyspace.dbs) {
+		return errorReply("ERR DB index is out of range")
+	}
+	h.db = index
+	return okReply()
+}
+
+func quit(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	return okReply()
+}
+
+func client(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	switch strings.ToLower(args[1]) {
+	case "setname":
+		if len(args) != 3 {
+			return syntaxErr
+		}
+		h.name = args[2]
+		return okReply()
+	case "getname":
+		if h.name == "" {
+			return nullReply()
+		}
+		return bulkReply(h.name)
+	}
+	return errorReply("ERR unknown subcommand '%s'", args[1])
+}
+
+func commandInfo(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	if len(args) > 1 && strings.EqualFold(args[1], "count") {
+		return intReply(int64(len(commands)))
+	}
+	// no command docs, clients fall back to their own tables.
+	return &redisgo.Resp{Kind: redisgo.ArrayKind}
+}
+
+func info(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	var b strings.Builder
+	b.WriteString("# Server\r\n")
+	b.WriteString("redis_version:7.0.0\r\n")
+	b.WriteString("redis_mode:standalone\r\n")
+	b.WriteString("server:go-netty\r\n")
+	fmt.Fprintf(&b, "uptime_in_seconds:%d\r\n", int64(time.Since(h.keyspace.started)/time.Second))
+	b.WriteString("\r\n# Keyspace\r\n")
+	for i, d := range h.keyspace.dbs {
+		if len(d.data) > 0 {
+			fmt.Fprintf(&b, "db%d:keys=%d,expires=%d,avg_ttl=0\r\n", i, len(d.data), len(d.expires))
+		}
+	}
+	return bulkReply(b.String())
+}
+
+func dbsize(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	return intReply(int64(db.size()))
+}
+
+func flushdb(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	db.flush()
+	return okReply()
+}
+
+func flushall(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	for _, d := range h.keyspace.dbs {
+		d.flush()
+	}
+	return okReply()
+}
+
+// keys
+
+func del(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	var n int64
+	for _, key := range args[1:] {
+		if _, ok := db.lookup(key); ok && db.remove(key) {
+			n++
+		}
+	}
+	return intReply(n)
+}
+
+func exists(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	var n int64
+	for _, key := range args[1:] {
+		if _, ok := db.lookup(key); ok {
+			n++
+		}
+	}
+	return intReply(n)
+}
+
+func typeOf(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	v, ok := db.lookup(args[1])
+	if !ok {
+		return simpleReply("none")
+	}
+	switch v.(type) {
+	case string:
+		return simpleReply("string")
+	case hashValue:
+		return simpleReply("hash")
+	case *listValue:
+		return simpleReply("list")
+	case setValue:
+		return simpleReply("set")
+	case zsetValue:
+		return simpleReply("zset")
+	}
+	return simpleReply("none")
+}
+
+func keys(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	return arrayReply(db.keys(args[1]))
+}
+
+func expire(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	n, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return notIntegerErr
+	}
+	unit := time.Second
+	if strings.EqualFold(args[0], "pexpire") {
+		unit = time.Millisecond
+	}
+	if _, ok := db.lookup(args[1]); !ok {
+		return intReply(0)
+	}
+	if n <= 0 {
+		db.remove(args[1])
+	} else {
+		db.expires[args[1]] = time.Now().Add(time.Duration(n) * unit)
+	}
+	return intReply(1)
+}
+
+func ttl(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	if _, ok := db.lookup(args[1]); !ok {
+		return intReply(-2)
+	}
+	deadline, ok := db.expires[args[1]]
+	if !ok {
+		return intReply(-1)
+	}
+	left := time.Until(deadline)
+	if strings.EqualFold(args[0], "pttl") {
+		return intReply(int64(left / time.Millisecond))
+	}
+	return intReply(int64((left + time.Second/2) / time.Second))
+}
+
+func persist(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	if _, ok := db.lookup(args[1]); !ok {
+		return intReply(0)
+	}
+	_, ok := db.expires[args[1]]
+	delete(db.expires, args[1])
+	return boolReply(ok)
+}
+
+// strings
+
+func get(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	s, ok, errReply := lookupAs[string](db, args[1])
+	switch {
+	case errReply != nil:
+		return errReply
+	case !ok:
+		return nullReply()
+	}
+	return bulkReply(s)
+}
+
+func set(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	var (
+		ttl                   time.Duration
+		nx, xx, keepTTL, load bool
+	)
+	for i := 3; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "GET":
+			load = true
+		case "EX", "PX":
+			if i+1 >= len(args) || ttl != 0 {
+				return syntaxErr
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return notIntegerErr
+			}
+			if n <= 0 {
+				return errorReply("ERR invalid expire time in 'set' command")
+			}
+			if ttl = time.Duration(n) * time.Second; option == "PX" {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+			i++
+		default:
+			return syntaxErr
+		}
+	}
+	if (nx && xx) || (keepTTL && ttl != 0) {
+		return syntaxErr
+	}
+
+	v, exists := db.lookup(args[1])
+	old, isString := v.(string)
+	if load && exists && !isString {
+		return wrongTypeErr
+	}
+
+	reply := okReply()
+	if load {
+		if reply = nullReply(); exists {
+			reply = bulkReply(old)
+		}
+	}
+
+	if (nx && exists) || (xx && !exists) {
+		if load {
+			return reply
+		}
+		return nullReply()
+	}
+
+	db.store(args[1], args[2])
+	switch {
+	case ttl > 0:
+		db.expires[args[1]] = time.Now().Add(ttl)
+	case !keepTTL:
+		delete(db.expires, args[1])
+	}
+	return reply
+}
+
+func mget(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	array := make([]redisgo.Resp, len(args)-1)
+	for i, key := range args[1:] {
+		if s, ok, errReply := lookupAs[string](db, key); ok && errReply == nil {
+			array[i] = *bulkReply(s)
+		} else {
+			array[i] = *nullReply()
+		}
+	}
+	return &redisgo.Resp{Kind: redisgo.ArrayKind, Array: array}
+}
+
+func mset(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	if len(args)%2 != 1 {
+		return errorReply("ERR wrong number of arguments for 'mset' command")
+	}
+	for i := 1; i < len(args); i += 2 {
+		db.store(args[i], args[i+1])
+		delete(db.expires, args[i])
+	}
+	return okReply()
+}
+
+func incrBy(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	delta := int64(1)
+	if len(args) == 3 {
+		var err error
+		if delta, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+			return notIntegerErr
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(args[0]), "decr") {
+		delta = -delta
+	}
+
+	s, ok, errReply := lookupAs[string](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	if ok {
+		var err error
+		if n, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return notIntegerErr
+		}
+	}
+	n, valid := addInt64(n, delta)
+	if !valid {
+		return overflowErr
+	}
+	db.store(args[1], strconv.FormatInt(n, 10))
+	return intReply(n)
+}
+
+func appendString(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	s, _, errReply := lookupAs[string](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	s += args[2]
+	db.store(args[1], s)
+	return intReply(int64(len(s)))
+}
+
+func strlen(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	s, _, errReply := lookupAs[string](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	return intReply(int64(len(s)))
+}
+
+// hashes
+
+func hset(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	if len(args)%2 != 0 {
+		return errorReply("ERR wrong number of arguments for 'hset' command")
+	}
+	hash, ok, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if !ok {
+		hash = hashValue{}
+		db.store(args[1], hash)
+	}
+	var n int64
+	for i := 2; i < len(args); i += 2 {
+		if _, exists := hash[args[i]]; !exists {
+			n++
+		}
+		hash[args[i]] = args[i+1]
+	}
+	return intReply(n)
+}
+
+func hget(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	hash, _, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if v, ok := hash[args[2]]; ok {
+		return bulkReply(v)
+	}
+	return nullReply()
+}
+
+func hmget(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	hash, _, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	array := make([]redisgo.Resp, len(args)-2)
+	for i, field := range args[2:] {
+		if v, ok := hash[field]; ok {
+			array[i] = *bulkReply(v)
+		} else {
+			array[i] = *nullReply()
+		}
+	}
+	return &redisgo.Resp{Kind: redisgo.ArrayKind, Array: array}
+}
+
+func hdel(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	hash, _, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	for _, field := range args[2:] {
+		if _, ok := hash[field]; ok {
+			delete(hash, field)
+			n++
+		}
+	}
+	if n > 0 && len(hash) == 0 {
+		db.remove(args[1])
+	}
+	return intReply(n)
+}
+
+// hgetall also serves HKEYS and HVALS.
+func hgetall(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	hash, _, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	items := make([]string, 0, 2*len(fields))
+	for _, field := range fields {
+		switch strings.ToLower(args[0]) {
+		case "hkeys":
+			items = append(items, field)
+		case "hvals":
+			items = append(items, hash[field])
+		default:
+			items = append(items, field, hash[field])
+		}
+	}
+	return arrayReply(items)
+}
+
+func hlen(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	hash, _, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	return intReply(int64(len(hash)))
+}
+
+func hexists(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	hash, _, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	_, ok := hash[args[2]]
+	return boolReply(ok)
+}
+
+func hincrby(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	delta, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return notIntegerErr
+	}
+	hash, ok, errReply := lookupAs[hashValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	if v, exists := hash[args[2]]; exists {
+		if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return errorReply("ERR hash value is not an integer")
+		}
+	}
+	n, valid := addInt64(n, delta)
+	if !valid {
+		return overflowErr
+	}
+	if !ok {
+		hash = hashValue{}
+		db.store(args[1], hash)
+	}
+	hash[args[2]] = strconv.FormatInt(n, 10)
+	return intReply(n)
+}
+
+// lists
+
+func push(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	list, ok, errReply := lookupAs[*listValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if !ok {
+		list = &listValue{}
+		db.store(args[1], list)
+	}
+	values := args[2:]
+	if strings.EqualFold(args[0], "lpush") {
+		// each value goes to the head in turn, so they end up reversed.
+		items := make([]string, len(values), len(values)+len(list.items))
+		for i, v := range values {
+			items[len(values)-1-i] = v
+		}
+		list.items = append(items, list.items...)
+	} else {
+		list.items = append(list.items, values...)
+	}
+	return intReply(int64(len(list.items)))
+}
+
+func pop(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	list, ok, errReply := lookupAs[*listValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if !ok {
+		return nullReply()
+	}
+	var v string
+	if strings.EqualFold(args[0], "lpop") {
+		v, list.items = list.items[0], list.items[1:]
+	} else {
+		v, list.items = list.items[len(list.items)-1], list.items[:len(list.items)-1]
+	}
+	if len(list.items) == 0 {
+		db.remove(args[1])
+	}
+	return bulkReply(v)
+}
+
+func llen(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	list, ok, errReply := lookupAs[*listValue](db, args[1])
+	if errReply != nil || !ok {
+		if errReply != nil {
+			return errReply
+		}
+		return intReply(0)
+	}
+	return intReply(int64(len(list.items)))
+}
+
+func lindex(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	index, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return notIntegerErr
+	}
+	list, ok, errReply := lookupAs[*listValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if !ok {
+		return nullReply()
+	}
+	if index < 0 {
+		index += int64(len(list.items))
+	}
+	if index < 0 || index >= int64(len(list.items)) {
+		return nullReply()
+	}
+	return bulkReply(list.items[index])
+}
+
+func lrange(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	start, err1 := strconv.ParseInt(args[2], 10, 64)
+	stop, err2 := strconv.ParseInt(args[3], 10, 64)
+	if err1 != nil || err2 != nil {
+		return notIntegerErr
+	}
+	list, ok, errReply := lookupAs[*listValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if !ok {
+		return arrayReply(nil)
+	}
+	from, to := normalizeRange(start, stop, len(list.items))
+	return arrayReply(list.items[from:to])
+}
+
+// sets
+
+func sadd(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	set, ok, errReply := lookupAs[setValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if !ok {
+		set = setValue{}
+		db.store(args[1], set)
+	}
+	var n int64
+	for _, member := range args[2:] {
+		if _, exists := set[member]; !exists {
+			set[member] = struct{}{}
+			n++
+		}
+	}
+	return intReply(n)
+}
+
+func srem(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	set, _, errReply := lookupAs[setValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	for _, member := range args[2:] {
+		if _, exists := set[member]; exists {
+			delete(set, member)
+			n++
+		}
+	}
+	if n > 0 && len(set) == 0 {
+		db.remove(args[1])
+	}
+	return intReply(n)
+}
+
+func smembers(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	set, _, errReply := lookupAs[setValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return arrayReply(members)
+}
+
+func sismember(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	set, _, errReply := lookupAs[setValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	_, ok := set[args[2]]
+	return boolReply(ok)
+}
+
+func scard(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	set, _, errReply := lookupAs[setValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	return intReply(int64(len(set)))
+}
+
+// sorted sets
+
+func zadd(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	if len(args)%2 != 0 {
+		return syntaxErr
+	}
+	scores := make([]float64, 0, (len(args)-2)/2)
+	for i := 2; i < len(args); i += 2 {
+		score, err := strconv.ParseFloat(args[i], 64)
+		if err != nil || math.IsNaN(score) {
+			return notFloatErr
+		}
+		scores = append(scores, score)
+	}
+
+	zset, ok, errReply := lookupAs[zsetValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if !ok {
+		zset = zsetValue{}
+		db.store(args[1], zset)
+	}
+	var n int64
+	for i, score := range scores {
+		member := args[3+2*i]
+		if _, exists := zset[member]; !exists {
+			n++
+		}
+		zset[member] = score
+	}
+	return intReply(n)
+}
+
+func zrem(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	zset, _, errReply := lookupAs[zsetValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	var n int64
+	for _, member := range args[2:] {
+		if _, exists := zset[member]; exists {
+			delete(zset, member)
+			n++
+		}
+	}
+	if n > 0 && len(zset) == 0 {
+		db.remove(args[1])
+	}
+	return intReply(n)
+}
+
+func zscore(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	zset, _, errReply := lookupAs[zsetValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if score, ok := zset[args[2]]; ok {
+		return bulkReply(formatScore(score))
+	}
+	return nullReply()
+}
+
+func zcard(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	zset, _, errReply := lookupAs[zsetValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	return intReply(int64(len(zset)))
+}
+
+func zincrby(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	delta, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || math.IsNaN(delta) {
+		return notFloatErr
+	}
+	zset, ok, errReply := lookupAs[zsetValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	// e.g. +inf plus -inf.
+	score := zset[args[3]] + delta
+	if math.IsNaN(score) {
+		return errorReply("ERR resulting score is not a number (NaN)")
+	}
+	if !ok {
+		zset = zsetValue{}
+		db.store(args[1], zset)
+	}
+	zset[args[3]] = score
+	return bulkReply(formatScore(score))
+}
+
+func zrange(h *commandHandler, db *database, args []string) *redisgo.Resp {
+	start, err1 := strconv.ParseInt(args[2], 10, 64)
+	stop, err2 := strconv.ParseInt(args[3], 10, 64)
+	if err1 != nil || err2 != nil {
+		return notIntegerErr
+	}
+	withScores := false
+	for _, option := range args[4:] {
+		if !strings.EqualFold(option, "withscores") {
+			return syntaxErr
+		}
+		withScores = true
+	}
+
+	zset, _, errReply := lookupAs[zsetValue](db, args[1])
+	if errReply != nil {
+		return errReply
+	}
+	members := zset.sorted()
+	from, to := normalizeRange(start, stop, len(members))
+
+	items := make([]string, 0, 2*(to-from))
+	for _, member := range members[from:to] {
+		items = append(items, member)
+		if withScores {
+			items = append(items, formatScore(zset[member]))
+		}
+	}
+	return arrayReply(items)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+func TestKeyspace_Execute(t *testing.T) {
+
+	// each test runs its commands in order on a new keyspace.
+	tests := []struct {
+		name     string
+		commands [][]string
+		want     []string
+	}{
+		{"connection", [][]string{{"PING"}, {"ping", "hi"}, {"ECHO", "x"}, {"SELECT", "16"}, {"FOO", "a"}, {"GET"}},
+			[]string{"+PONG", "$2 hi", "$1 x", "-ERR DB index is out of range",
+				"-ERR unknown command 'FOO', with args beginning with: 'a'", "-ERR wrong number of arguments for 'get' command"}},
+		{"strings", [][]string{{"SET", "k", "v"}, {"GET", "k"}, {"SET", "k", "w", "NX"}, {"APPEND", "k", "x"}, {"STRLEN", "k"}, {"GET", "none"}},
+			[]string{"+OK", "$1 v", "$-1", ":2", ":2", "$-1"}},
+		{"incr", [][]string{{"INCR", "n"}, {"INCRBY", "n", "9"}, {"DECR", "n"}, {"SET", "n", "9223372036854775807"}, {"INCR", "n"}, {"INCR", "s"}, {"SET", "s", "x"}, {"INCR", "s"}},
+			[]string{":1", ":10", ":9", "+OK", "-ERR increment or decrement would overflow", ":1", "+OK", "-ERR value is not an integer or out of range"}},
+		{"hashes", [][]string{{"HSET", "h", "a", "1", "b", "2"}, {"HGET", "h", "a"}, {"HLEN", "h"}, {"HINCRBY", "h", "a", "5"}, {"HDEL", "h", "b"}, {"HGETALL", "h"}},
+			[]string{":2", "$1 1", ":2", ":6", ":1", "*2 $1 a $1 6"}},
+		{"hincrby-overflow", [][]string{{"HSET", "h", "n", "-9223372036854775808"}, {"HINCRBY", "h", "n", "-1"}, {"HGET", "h", "n"}},
+			[]string{":1", "-ERR increment or decrement would overflow", "$20 -9223372036854775808"}},
+		{"lists", [][]string{{"LPUSH", "l", "a", "b", "c"}, {"RPUSH", "l", "d"}, {"LRANGE", "l", "0", "-1"}, {"LPOP", "l"}, {"RPOP", "l"}, {"LINDEX", "l", "-1"}, {"LLEN", "l"}},
+			[]string{":3", ":4", "*4 $1 c $1 b $1 a $1 d", "$1 c", "$1 d", "$1 a", ":2"}},
+		{"sets", [][]string{{"SADD", "s", "a", "b", "a"}, {"SISMEMBER", "s", "a"}, {"SREM", "s", "a"}, {"SMEMBERS", "s"}, {"SCARD", "s"}},
+			[]string{":2", ":1", ":1", "*1 $1 b", ":1"}},
+		{"sorted-sets", [][]string{{"ZADD", "z", "1", "a", "2", "b"}, {"ZINCRBY", "z", "2", "a"}, {"ZRANGE", "z", "0", "-1", "WITHSCORES"}, {"ZSCORE", "z", "b"}, {"ZCARD", "z"}},
+			[]string{":2", "$1 3", "*4 $1 b $1 2 $1 a $1 3", "$1 2", ":2"}},
+		{"zincrby-nan", [][]string{{"ZADD", "z", "+inf", "a"}, {"ZINCRBY", "z", "-inf", "a"}, {"ZSCORE", "z", "a"}, {"ZINCRBY", "y", "nan", "a"}, {"EXISTS", "y"}},
+			[]string{":1", "-ERR resulting score is not a number (NaN)", "$3 inf", "-ERR value is not a valid float", ":0"}},
+		{"keys", [][]string{{"MSET", "a", "1", "b", "2"}, {"KEYS", "*"}, {"TYPE", "a"}, {"EXPIRE", "a", "100"}, {"TTL", "a"}, {"PERSIST", "a"}, {"TTL", "a"}, {"DEL", "a", "c"}, {"DBSIZE"}},
+			[]string{"+OK", "*2 $1 a $1 b", "+string", ":1", ":100", ":1", ":-1", ":1", ":1"}},
+		{"wrong-type", [][]string{{"LPUSH", "l", "a"}, {"GET", "l"}, {"HSET", "l", "a", "b"}},
+			[]string{":1", "-WRONGTYPE Operation against a key holding the wrong kind of value", "-WRONGTYPE Operation against a key holding the wrong kind of value"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &commandHandler{keyspace: newKeyspace(16)}
+			for i, args := range tt.commands {
+				if got := flatten(h.keyspace.execute(h, args)); got != tt.want[i] {
+					t.Errorf("%s = %q, want %q", strings.Join(args, " "), got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKeyspace_Expire(t *testing.T) {
+
+	h := &commandHandler{keyspace: newKeyspace(16)}
+	for _, args := range [][]string{{"SET", "a", "1", "PX", "1"}, {"SET", "b", "2"}} {
+		h.keyspace.execute(h, args)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// expired keys are gone before they are reaped.
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"DBSIZE"}, ":1"},
+		{[]string{"GET", "a"}, "$-1"},
+		{[]string{"KEYS", "*"}, "*1 $1 b"},
+	} {
+		if got := flatten(h.keyspace.execute(h, tt.args)); got != tt.want {
+			t.Errorf("%s = %q, want %q", strings.Join(tt.args, " "), got, tt.want)
+		}
+	}
+}
+
+// flatten returns the encoded reply with spaces for CRLF, e.g. "*1 $1 b".
+func flatten(resp *redisgo.Resp) string {
+	return strings.TrimSpace(strings.ReplaceAll(resp.String(), "\r\n", " "))
+}
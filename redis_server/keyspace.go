@@ -0,0 +1,207 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// value types stored in a database.
+type (
+	hashValue map[string]string
+	listValue struct{ items []string }
+	setValue  map[string]struct{}
+	zsetValue map[string]float64
+)
+
+type database struct {
+	data    map[string]interface{}
+	expires map[string]time.Time
+}
+
+func newDatabase() *database {
+	return &database{
+		data:    make(map[string]interface{}),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// lookup returns the value of key, removing it first if it has expired.
+func (db *database) lookup(key string) (interface{}, bool) {
+	if deadline, ok := db.expires[key]; ok && !time.Now().Before(deadline) {
+		db.remove(key)
+	}
+	v, ok := db.data[key]
+	return v, ok
+}
+
+func (db *database) store(key string, value interface{}) {
+	db.data[key] = value
+}
+
+func (db *database) remove(key string) bool {
+	_, ok := db.data[key]
+	delete(db.data, key)
+	delete(db.expires, key)
+	return ok
+}
+
+func (db *database) flush() {
+	db.data = make(map[string]interface{})
+	db.expires = make(map[string]time.Time)
+}
+
+// size returns the number of live keys.
+func (db *database) size() int {
+	db.reap(time.Now())
+	return len(db.data)
+}
+
+// reap removes the keys expired at now.
+func (db *database) reap(now time.Time) {
+	for key, deadline := range db.expires {
+		if !now.Before(deadline) {
+			db.remove(key)
+		}
+	}
+}
+
+// keys returns the live keys matching pattern in sorted order.
+func (db *database) keys(pattern string) []string {
+	keys := make([]string, 0, len(db.data))
+	for key := range db.data {
+		if _, ok := db.lookup(key); ok && matchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type keyspace struct {
+	mutex   sync.Mutex
+	dbs     []*database
+	started time.Time
+}
+
+func newKeyspace(databases int) *keyspace {
+	ks := &keyspace{dbs: make([]*database, databases), started: time.Now()}
+	for i := range ks.dbs {
+		ks.dbs[i] = newDatabase()
+	}
+	return ks
+}
+
+// expireLoop actively removes expired keys that are never accessed again.
+func (ks *keyspace) expireLoop(interval time.Duration) {
+	for range time.Tick(interval) {
+		now := time.Now()
+		ks.mutex.Lock()
+		for _, db := range ks.dbs {
+			db.reap(now)
+		}
+		ks.mutex.Unlock()
+	}
+}
+
+// sorted returns the members ordered by score, then lexicographically.
+func (z zsetValue) sorted() []string {
+	members := make([]string, 0, len(z))
+	for member := range z {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		si, sj := z[members[i]], z[members[j]]
+		if si != sj {
+			return si < sj
+		}
+		return members[i] < members[j]
+	})
+	return members
+}
+
+// matchPattern reports whether s matches the glob-style pattern used by
+// KEYS, supporting '*', '?', '[...]' classes and '\' escapes.
+func matchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := 1
+			not := end < len(pattern) && pattern[end] == '^'
+			if not {
+				end++
+			}
+			match := false
+			for ; end < len(pattern) && pattern[end] != ']'; end++ {
+				switch {
+				case pattern[end] == '\\' && end+1 < len(pattern):
+					end++
+					match = match || pattern[end] == s[0]
+				case end+2 < len(pattern) && pattern[end+1] == '-' && pattern[end+2] != ']':
+					lo, hi := pattern[end], pattern[end+2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					match = match || (s[0] >= lo && s[0] <= hi)
+					end += 2
+				default:
+					match = match || pattern[end] == s[0]
+				}
+			}
+			if match == not {
+				return false
+			}
+			pattern = pattern[min(end, len(pattern)-1):]
+			s = s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(s) == 0
+}
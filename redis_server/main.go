@@ -0,0 +1,45 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-netty/go-netty"
+)
+
+func main() {
+
+	// shared by every connection.
+	keyspace := newKeyspace(16)
+	go keyspace.expireLoop(time.Second)
+
+	// setup child pipeline initializer.
+	childInitializer := func(channel netty.Channel) {
+		channel.Pipeline().
+			AddLast(&respServerCodec{}, &commandHandler{keyspace: keyspace})
+	}
+
+	fmt.Println("redis server listening on :6379")
+
+	// setup bootstrap & startup server.
+	err := netty.NewBootstrap(netty.WithChildInitializer(childInitializer)).
+		Listen("0.0.0.0:6379").Sync()
+
+	fmt.Println("exited", err)
+}
@@ -0,0 +1,95 @@
+/*
+ *  Copyright 2019 the go-netty project
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *       https://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-netty/go-netty"
+	"github.com/go-netty/go-netty-samples/redis_cli/redisgo"
+)
+
+// respServerCodec decodes multibulk and inline commands into []string and
+// encodes *redisgo.Resp replies.
+type respServerCodec struct {
+	decoder *redisgo.CommandDecoder
+}
+
+func (s *respServerCodec) CodecName() string {
+	return "resp-server-codec"
+}
+
+func (s *respServerCodec) HandleRead(ctx netty.InboundContext, message netty.Message) {
+
+	// init decoder.
+	if nil == s.decoder {
+		s.decoder = redisgo.NewCommandDecoder(message.(io.Reader), 64*1024)
+	}
+
+	// decode redis command.
+	req, err := s.decoder.Decode()
+	if nil != err {
+		// malformed commands are answered before the connection is closed,
+		// the reply is encoded here as the write starts below this codec.
+		var reply *redisgo.Resp
+		var limitErr *redisgo.LimitError
+		switch {
+		case errors.As(err, new(redisgo.ProtocolError)):
+			reply = errorReply("ERR %v", err)
+		case errors.As(err, &limitErr):
+			reply = errorReply("ERR Protocol error: %v", err)
+		}
+		if nil != reply {
+			buffer := bytes.NewBuffer(nil)
+			if nil == redisgo.EncodeResp(buffer, reply) {
+				ctx.Write(buffer)
+			}
+		}
+		ctx.Close(err)
+		return
+	}
+
+	args := make([]string, len(req))
+	for i := range req {
+		args[i] = string(req[i])
+	}
+
+	// post command.
+	ctx.HandleRead(args)
+}
+
+func (s *respServerCodec) HandleWrite(ctx netty.OutboundContext, message netty.Message) {
+
+	v, ok := message.(*redisgo.Resp)
+	if !ok {
+		ctx.Close(fmt.Errorf("%T is invalid message", message))
+		return
+	}
+
+	// encode reply.
+	buffer := bytes.NewBuffer(nil)
+	if err := redisgo.EncodeResp(buffer, v); nil != err {
+		ctx.Close(err)
+		return
+	}
+
+	// post reply.
+	ctx.HandleWrite(buffer)
+}